@@ -1,26 +1,158 @@
 package config
 
 import (
+	"crypto/subtle"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
+	"sync"
+	"sync/atomic"
 
 	"github.com/hashicorp/hcl/v2/hclsimple"
 )
 
+var (
+	current       atomic.Value
+	listeners     []func(*Config)
+	listenersLock = &sync.Mutex{}
+	updateLock    = &sync.Mutex{}
+)
+
 // Config represents global configs container
 type Config struct {
 	Server struct {
 		Redis struct {
-			ListenAddr  string `hcl:"listen"`
-			AsyncWrites bool   `hcl:"async"`
-			MaxConns    int64  `hcl:"max_connections"`
+			ListenAddr      string             `hcl:"listen,optional"`
+			UnixSocket      string             `hcl:"unix_socket,optional"`
+			UnixSocketPerms string             `hcl:"unix_socket_permissions,optional"`
+			AsyncWrites     bool               `hcl:"async"`
+			MaxConns        int64              `hcl:"max_connections"`
+			Timeout         int64              `hcl:"timeout,optional"`
+			ReadOnly        bool               `hcl:"read_only,optional"`
+			TLS             *TLSConfig         `hcl:"tls,block"`
+			Slowlog         *SlowlogConfig     `hcl:"slowlog,block"`
+			WriteBehind     *WriteBehindConfig `hcl:"write_behind,block"`
+			Replication     *ReplicationConfig `hcl:"replication,block"`
+			Scripting       *ScriptingConfig   `hcl:"scripting,block"`
 		} `hcl:"redis,block"`
+
+		HTTP      *HTTPConfig      `hcl:"http,block"`
+		Memcached *MemcachedConfig `hcl:"memcached,block"`
+		GRPC      *GRPCConfig      `hcl:"grpc,block"`
+
+		Metrics *MetricsConfig `hcl:"metrics,block"`
+
+		WatchConfig     bool  `hcl:"watch_config,optional"`
+		ShutdownTimeout int64 `hcl:"shutdown_timeout,optional"`
 	} `hcl:"server,block"`
 
-	Engine struct {
-		Driver string `hcl:"driver,label"`
-		DSN    string `hcl:"dsn"`
-	} `hcl:"engine,block"`
+	Engines []*EngineConfig `hcl:"engine,block"`
+	Routes  []*RouteConfig  `hcl:"route,block"`
+	Plugins []*PluginConfig `hcl:"plugin,block"`
+	Users   []*UserConfig   `hcl:"user,block"`
+
+	// Filename the file the configs have been loaded from
+	Filename string
+}
+
+// EngineConfig represents the configs of a storage engine
+type EngineConfig struct {
+	Driver     string       `hcl:"driver,label"`
+	Name       string       `hcl:"name,optional"`
+	DSN        string       `hcl:"dsn,optional"`
+	ReplicaDSN string       `hcl:"replica_dsn,optional"`
+	Shards     []string     `hcl:"shards,optional"`
+	Cache      *CacheConfig `hcl:"cache,block"`
+}
+
+// RouteConfig represents a rule routing the keys of a db index and/or a key prefix to a named engine
+type RouteConfig struct {
+	DB     *int   `hcl:"db,optional"`
+	Prefix string `hcl:"prefix,optional"`
+	Engine string `hcl:"engine"`
+}
+
+// PluginConfig represents a WebAssembly module providing custom commands
+type PluginConfig struct {
+	Name      string `hcl:"name,label"`
+	Path      string `hcl:"path"`
+	TimeLimit int64  `hcl:"time_limit,optional"`
+}
+
+// UserConfig represents a user of the http interface, once a user is configured the clients must authenticate,
+// and each user may only reach the channels matching its channels patterns (as path.Match)
+type UserConfig struct {
+	Name     string   `hcl:"name,label"`
+	Password string   `hcl:"password"`
+	Channels []string `hcl:"channels,optional"`
+}
+
+// TLSConfig represents the configs of a TLS enabled listener
+type TLSConfig struct {
+	ListenAddr   string `hcl:"listen,optional"`
+	CertFile     string `hcl:"cert_file"`
+	KeyFile      string `hcl:"key_file"`
+	ClientCAFile string `hcl:"client_ca_file,optional"`
+	MinVersion   string `hcl:"min_version,optional"`
+}
+
+// SlowlogConfig represents the configs of the slow commands log
+type SlowlogConfig struct {
+	LogSlowerThan *int64 `hcl:"log_slower_than,optional"`
+	MaxLen        int    `hcl:"max_len,optional"`
+}
+
+// WriteBehindConfig represents the configs of the queue used to apply the async writes
+type WriteBehindConfig struct {
+	QueueSize  int    `hcl:"queue_size,optional"`
+	Workers    int    `hcl:"workers,optional"`
+	BatchSize  int    `hcl:"batch_size,optional"`
+	MaxRetries int    `hcl:"max_retries,optional"`
+	Journal    string `hcl:"journal,optional"`
+}
+
+// ReplicationConfig represents the configs of the replication between the redix nodes
+type ReplicationConfig struct {
+	ReplicaOf   string `hcl:"replica_of,optional"`
+	BacklogSize int    `hcl:"backlog_size,optional"`
+}
+
+// ScriptingConfig represents the configs of the Lua scripts execution
+type ScriptingConfig struct {
+	TimeLimit int64 `hcl:"time_limit,optional"`
+}
+
+// CacheConfig represents the configs of the in-memory cache in front of the engine
+type CacheConfig struct {
+	Policy      string `hcl:"policy,optional"`
+	MaxKeys     int    `hcl:"max_keys,optional"`
+	TTL         int64  `hcl:"ttl,optional"`
+	Distributed bool   `hcl:"distributed,optional"`
+	Channel     string `hcl:"channel,optional"`
+}
+
+// HTTPConfig represents the configs of the http interface
+type HTTPConfig struct {
+	ListenAddr     string   `hcl:"listen"`
+	AllowedOrigins []string `hcl:"allowed_origins,optional"`
+}
+
+// MemcachedConfig represents the configs of the memcached interface
+type MemcachedConfig struct {
+	ListenAddr string `hcl:"listen"`
+	DB         int    `hcl:"db,optional"`
+}
+
+// GRPCConfig represents the configs of the grpc interface, it uses the tls configs of the redis listener if set
+type GRPCConfig struct {
+	ListenAddr string `hcl:"listen"`
+}
+
+// MetricsConfig represents the configs of the prometheus metrics listener
+type MetricsConfig struct {
+	ListenAddr string `hcl:"listen"`
+	Path       string `hcl:"path,optional"`
 }
 
 // Unmarshal parses the specified filename and load it into memory
@@ -38,5 +170,280 @@ func Unmarshal(filename string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := cfg.validateEngines(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validatePlugins(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validateUsers(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Server.Memcached != nil && cfg.Server.Memcached.DB < 0 {
+		return nil, fmt.Errorf("the memcached db must not be negative")
+	}
+
+	cfg.Filename = filename
+
 	return &cfg, nil
 }
+
+// validateEngines names the unnamed engines after their drivers then validates the engines and the routes
+func (cfg *Config) validateEngines() error {
+	if len(cfg.Engines) < 1 {
+		return fmt.Errorf("at least one engine must be specified")
+	}
+
+	names := map[string]bool{}
+
+	for _, engineCfg := range cfg.Engines {
+		if engineCfg.Name == "" {
+			engineCfg.Name = engineCfg.Driver
+		}
+
+		if names[engineCfg.Name] {
+			return fmt.Errorf("duplicate engine name (%s), use the name attribute to distinguish the engines", engineCfg.Name)
+		}
+
+		names[engineCfg.Name] = true
+	}
+
+	// the shards are only reachable through the engine they belong to
+	shards := map[string]bool{}
+
+	for _, engineCfg := range cfg.Engines {
+		for _, shard := range engineCfg.Shards {
+			if !names[shard] || shard == engineCfg.Name {
+				return fmt.Errorf("invalid shard (%s) specified in the engine (%s)", shard, engineCfg.Name)
+			}
+
+			if shards[shard] {
+				return fmt.Errorf("the engine (%s) is used as a shard more than once", shard)
+			}
+
+			shards[shard] = true
+		}
+	}
+
+	if shards[cfg.DefaultEngine().Name] {
+		return fmt.Errorf("the default engine (%s) can't be a shard", cfg.DefaultEngine().Name)
+	}
+
+	for _, routeCfg := range cfg.Routes {
+		if !names[routeCfg.Engine] || shards[routeCfg.Engine] {
+			return fmt.Errorf("unknown engine (%s) specified in a route", routeCfg.Engine)
+		}
+
+		if routeCfg.DB == nil && routeCfg.Prefix == "" {
+			return fmt.Errorf("the route to the engine (%s) must specify a db and/or a prefix", routeCfg.Engine)
+		}
+	}
+
+	return nil
+}
+
+// validatePlugins validates the plugins names and paths
+func (cfg *Config) validatePlugins() error {
+	names := map[string]bool{}
+
+	for _, pluginCfg := range cfg.Plugins {
+		if names[pluginCfg.Name] {
+			return fmt.Errorf("duplicate plugin name (%s)", pluginCfg.Name)
+		}
+
+		if pluginCfg.Path == "" {
+			return fmt.Errorf("the plugin (%s) must specify the path of its module", pluginCfg.Name)
+		}
+
+		names[pluginCfg.Name] = true
+	}
+
+	return nil
+}
+
+// validateUsers validates the users names, passwords and channels patterns
+func (cfg *Config) validateUsers() error {
+	names := map[string]bool{}
+
+	for _, userCfg := range cfg.Users {
+		if names[userCfg.Name] {
+			return fmt.Errorf("duplicate user name (%s)", userCfg.Name)
+		}
+
+		if userCfg.Password == "" {
+			return fmt.Errorf("the user (%s) must specify a password", userCfg.Name)
+		}
+
+		for _, pattern := range userCfg.Channels {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid channels pattern (%s) specified in the user (%s)", pattern, userCfg.Name)
+			}
+		}
+
+		names[userCfg.Name] = true
+	}
+
+	return nil
+}
+
+// User returns the configured user having the specified name, nil if there is none
+func (cfg *Config) User(name string) *UserConfig {
+	for _, userCfg := range cfg.Users {
+		if userCfg.Name == name {
+			return userCfg
+		}
+	}
+
+	return nil
+}
+
+// Authenticate returns the configured user having the specified credentials, nil if there is none
+func (cfg *Config) Authenticate(username, password string) *UserConfig {
+	user := cfg.User(username)
+	if user == nil || subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return nil
+	}
+
+	return user
+}
+
+// DefaultEngine returns the engine that stores the keys not matching any route, it is the first declared one
+func (cfg *Config) DefaultEngine() *EngineConfig {
+	return cfg.Engines[0]
+}
+
+// Clone returns a deep copy of the configs
+func (cfg *Config) Clone() *Config {
+	clone := *cfg
+
+	if cfg.Server.Redis.TLS != nil {
+		tlsCfg := *cfg.Server.Redis.TLS
+		clone.Server.Redis.TLS = &tlsCfg
+	}
+
+	if cfg.Server.Redis.Slowlog != nil {
+		slowlogCfg := *cfg.Server.Redis.Slowlog
+		if slowlogCfg.LogSlowerThan != nil {
+			logSlowerThan := *slowlogCfg.LogSlowerThan
+			slowlogCfg.LogSlowerThan = &logSlowerThan
+		}
+		clone.Server.Redis.Slowlog = &slowlogCfg
+	}
+
+	if cfg.Server.Redis.WriteBehind != nil {
+		writeBehindCfg := *cfg.Server.Redis.WriteBehind
+		clone.Server.Redis.WriteBehind = &writeBehindCfg
+	}
+
+	clone.Engines = make([]*EngineConfig, len(cfg.Engines))
+	for i, engineCfg := range cfg.Engines {
+		engineCfgCopy := *engineCfg
+		engineCfgCopy.Shards = append([]string(nil), engineCfg.Shards...)
+		if engineCfg.Cache != nil {
+			cacheCfg := *engineCfg.Cache
+			engineCfgCopy.Cache = &cacheCfg
+		}
+		clone.Engines[i] = &engineCfgCopy
+	}
+
+	clone.Routes = make([]*RouteConfig, len(cfg.Routes))
+	for i, routeCfg := range cfg.Routes {
+		routeCfgCopy := *routeCfg
+		clone.Routes[i] = &routeCfgCopy
+	}
+
+	clone.Plugins = make([]*PluginConfig, len(cfg.Plugins))
+	for i, pluginCfg := range cfg.Plugins {
+		pluginCfgCopy := *pluginCfg
+		clone.Plugins[i] = &pluginCfgCopy
+	}
+
+	clone.Users = make([]*UserConfig, len(cfg.Users))
+	for i, userCfg := range cfg.Users {
+		userCfgCopy := *userCfg
+		userCfgCopy.Channels = append([]string(nil), userCfg.Channels...)
+		clone.Users[i] = &userCfgCopy
+	}
+
+	if cfg.Server.Redis.Replication != nil {
+		replicationCfg := *cfg.Server.Redis.Replication
+		clone.Server.Redis.Replication = &replicationCfg
+	}
+
+	if cfg.Server.Redis.Scripting != nil {
+		scriptingCfg := *cfg.Server.Redis.Scripting
+		clone.Server.Redis.Scripting = &scriptingCfg
+	}
+
+	if cfg.Server.HTTP != nil {
+		httpCfg := *cfg.Server.HTTP
+		httpCfg.AllowedOrigins = append([]string(nil), cfg.Server.HTTP.AllowedOrigins...)
+		clone.Server.HTTP = &httpCfg
+	}
+
+	if cfg.Server.Memcached != nil {
+		memcachedCfg := *cfg.Server.Memcached
+		clone.Server.Memcached = &memcachedCfg
+	}
+
+	if cfg.Server.GRPC != nil {
+		grpcCfg := *cfg.Server.GRPC
+		clone.Server.GRPC = &grpcCfg
+	}
+
+	if cfg.Server.Metrics != nil {
+		metricsCfg := *cfg.Server.Metrics
+		clone.Server.Metrics = &metricsCfg
+	}
+
+	return &clone
+}
+
+// Current returns the currently active configs
+func Current() *Config {
+	cfg, _ := current.Load().(*Config)
+
+	return cfg
+}
+
+// Store replaces the currently active configs by the specified one
+// and notifies the registered change listeners.
+// the stored configs must not be modified afterwards, use Clone instead.
+func Store(cfg *Config) {
+	listenersLock.Lock()
+	defer listenersLock.Unlock()
+
+	current.Store(cfg)
+
+	for _, fn := range listeners {
+		fn(cfg)
+	}
+}
+
+// Update applies fn to a copy of the current configs, then stores
+// that copy only if fn succeeded, concurrent updates are serialized.
+func Update(fn func(*Config) error) error {
+	updateLock.Lock()
+	defer updateLock.Unlock()
+
+	cfg := Current().Clone()
+
+	if err := fn(cfg); err != nil {
+		return err
+	}
+
+	Store(cfg)
+
+	return nil
+}
+
+// OnChange registers a listener to be called whenever new configs are stored
+func OnChange(fn func(*Config)) {
+	listenersLock.Lock()
+	defer listenersLock.Unlock()
+
+	listeners = append(listeners, fn)
+}
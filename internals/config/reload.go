@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Reload re-reads the file the current configs have been loaded from, then stores
+// new configs having the changed runtime mutable settings and users applied on top of the current ones.
+// it returns the names of the applied settings and the ones that require a restart,
+// the current configs remain untouched if the file couldn't be loaded or is invalid.
+func Reload() (applied []string, ignored []string, err error) {
+	cur := Current()
+	if cur == nil {
+		return nil, nil, fmt.Errorf("there are no loaded configs to reload")
+	}
+
+	loaded, err := Unmarshal(cur.Filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = Update(func(cfg *Config) error {
+		for _, setting := range settings {
+			val := setting.Get(loaded)
+			if val == setting.Get(cfg) {
+				continue
+			}
+
+			if !setting.Mutable {
+				ignored = append(ignored, setting.Name)
+				continue
+			}
+
+			if err := setting.Set(cfg, val); err != nil {
+				return err
+			}
+
+			applied = append(applied, setting.Name)
+		}
+
+		// the users are swapped as a whole, so the credentials are rotated without a restart
+		if !reflect.DeepEqual(loaded.Users, cfg.Users) {
+			cfg.Users = loaded.Users
+			applied = append(applied, "users")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return applied, ignored, nil
+}
+
+// Watch polls the specified file every interval and calls fn whenever its modification time changes
+func Watch(filename string, interval time.Duration, fn func()) {
+	var lastModTime time.Time
+
+	if stat, err := os.Stat(filename); err == nil {
+		lastModTime = stat.ModTime()
+	}
+
+	for range time.Tick(interval) {
+		stat, err := os.Stat(filename)
+		if err != nil {
+			continue
+		}
+
+		if !stat.ModTime().Equal(lastModTime) {
+			lastModTime = stat.ModTime()
+			fn()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Rewrite persists the mutable settings of the specified configs into the file
+// they have been loaded from, the rest of the file (including the comments) is kept as is.
+func Rewrite(cfg *Config) error {
+	if cfg.Filename == "" {
+		return fmt.Errorf("the configs haven't been loaded from a file")
+	}
+
+	src, err := ioutil.ReadFile(cfg.Filename)
+	if err != nil {
+		return err
+	}
+
+	file, diags := hclwrite.ParseConfig(src, cfg.Filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	for _, setting := range settings {
+		if !setting.Mutable {
+			continue
+		}
+
+		body := file.Body()
+
+		for _, name := range setting.Block {
+			block := body.FirstMatchingBlock(name, nil)
+			if block == nil {
+				body.AppendNewline()
+				block = body.AppendNewBlock(name, nil)
+			}
+
+			body = block.Body()
+		}
+
+		body.SetAttributeValue(setting.Attr, setting.hcl(cfg))
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(cfg.Filename), filepath.Base(cfg.Filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(file.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if stat, err := os.Stat(cfg.Filename); err == nil {
+		os.Chmod(tmp.Name(), stat.Mode())
+	}
+
+	return os.Rename(tmp.Name(), cfg.Filename)
+}
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Global consts
+const (
+	DefaultSlowlogLogSlowerThan = 10000
+	DefaultSlowlogMaxLen        = 128
+	DefaultScriptingTimeLimit   = 5000
+	DefaultPluginTimeLimit      = 5000
+)
+
+// Setting represents a setting that can be inspected (and optionally changed) at runtime
+type Setting struct {
+	// Name the name of the setting as used by CONFIG GET/SET
+	Name string
+
+	// Mutable whether the setting can be changed at runtime or not
+	Mutable bool
+
+	// Block the path of the hcl block the setting is declared in
+	Block []string
+
+	// Attr the hcl attribute name of the setting
+	Attr string
+
+	get func(*Config) string
+	set func(*Config, string) error
+	hcl func(*Config) cty.Value
+}
+
+var (
+	settings = []*Setting{
+		{
+			Name:  "listen",
+			Block: []string{"server", "redis"},
+			Attr:  "listen",
+			get: func(cfg *Config) string {
+				return cfg.Server.Redis.ListenAddr
+			},
+		},
+		{
+			Name:  "unix_socket",
+			Block: []string{"server", "redis"},
+			Attr:  "unix_socket",
+			get: func(cfg *Config) string {
+				return cfg.Server.Redis.UnixSocket
+			},
+		},
+		{
+			Name:    "max_connections",
+			Mutable: true,
+			Block:   []string{"server", "redis"},
+			Attr:    "max_connections",
+			get: func(cfg *Config) string {
+				return strconv.FormatInt(cfg.Server.Redis.MaxConns, 10)
+			},
+			set: func(cfg *Config, val string) error {
+				n, err := parseInt(val, 0)
+				if err != nil {
+					return err
+				}
+
+				cfg.Server.Redis.MaxConns = n
+
+				return nil
+			},
+			hcl: func(cfg *Config) cty.Value {
+				return cty.NumberIntVal(cfg.Server.Redis.MaxConns)
+			},
+		},
+		{
+			Name:    "async",
+			Mutable: true,
+			Block:   []string{"server", "redis"},
+			Attr:    "async",
+			get: func(cfg *Config) string {
+				return strconv.FormatBool(cfg.Server.Redis.AsyncWrites)
+			},
+			set: func(cfg *Config, val string) error {
+				b, err := parseBool(val)
+				if err != nil {
+					return err
+				}
+
+				cfg.Server.Redis.AsyncWrites = b
+
+				return nil
+			},
+			hcl: func(cfg *Config) cty.Value {
+				return cty.BoolVal(cfg.Server.Redis.AsyncWrites)
+			},
+		},
+		{
+			Name:    "read_only",
+			Mutable: true,
+			Block:   []string{"server", "redis"},
+			Attr:    "read_only",
+			get: func(cfg *Config) string {
+				return strconv.FormatBool(cfg.Server.Redis.ReadOnly)
+			},
+			set: func(cfg *Config, val string) error {
+				b, err := parseBool(val)
+				if err != nil {
+					return err
+				}
+
+				cfg.Server.Redis.ReadOnly = b
+
+				return nil
+			},
+			hcl: func(cfg *Config) cty.Value {
+				return cty.BoolVal(cfg.Server.Redis.ReadOnly)
+			},
+		},
+		{
+			Name:    "timeout",
+			Mutable: true,
+			Block:   []string{"server", "redis"},
+			Attr:    "timeout",
+			get: func(cfg *Config) string {
+				return strconv.FormatInt(cfg.Server.Redis.Timeout, 10)
+			},
+			set: func(cfg *Config, val string) error {
+				n, err := parseInt(val, 0)
+				if err != nil {
+					return err
+				}
+
+				cfg.Server.Redis.Timeout = n
+
+				return nil
+			},
+			hcl: func(cfg *Config) cty.Value {
+				return cty.NumberIntVal(cfg.Server.Redis.Timeout)
+			},
+		},
+		{
+			Name:    "slowlog.log_slower_than",
+			Mutable: true,
+			Block:   []string{"server", "redis", "slowlog"},
+			Attr:    "log_slower_than",
+			get: func(cfg *Config) string {
+				return strconv.FormatInt(*cfg.SlowlogConfig().LogSlowerThan, 10)
+			},
+			set: func(cfg *Config, val string) error {
+				n, err := parseInt(val, -1)
+				if err != nil {
+					return err
+				}
+
+				slowlogCfg := cfg.SlowlogConfig()
+				slowlogCfg.LogSlowerThan = &n
+				cfg.Server.Redis.Slowlog = slowlogCfg
+
+				return nil
+			},
+			hcl: func(cfg *Config) cty.Value {
+				return cty.NumberIntVal(*cfg.SlowlogConfig().LogSlowerThan)
+			},
+		},
+		{
+			Name:    "slowlog.max_len",
+			Mutable: true,
+			Block:   []string{"server", "redis", "slowlog"},
+			Attr:    "max_len",
+			get: func(cfg *Config) string {
+				return strconv.Itoa(cfg.SlowlogConfig().MaxLen)
+			},
+			set: func(cfg *Config, val string) error {
+				n, err := parseInt(val, 1)
+				if err != nil {
+					return err
+				}
+
+				slowlogCfg := cfg.SlowlogConfig()
+				slowlogCfg.MaxLen = int(n)
+				cfg.Server.Redis.Slowlog = slowlogCfg
+
+				return nil
+			},
+			hcl: func(cfg *Config) cty.Value {
+				return cty.NumberIntVal(int64(cfg.SlowlogConfig().MaxLen))
+			},
+		},
+		{
+			Name:    "scripting.time_limit",
+			Mutable: true,
+			Block:   []string{"server", "redis", "scripting"},
+			Attr:    "time_limit",
+			get: func(cfg *Config) string {
+				return strconv.FormatInt(cfg.ScriptingConfig().TimeLimit, 10)
+			},
+			set: func(cfg *Config, val string) error {
+				n, err := parseInt(val, 0)
+				if err != nil {
+					return err
+				}
+
+				scriptingCfg := cfg.ScriptingConfig()
+				scriptingCfg.TimeLimit = n
+				cfg.Server.Redis.Scripting = scriptingCfg
+
+				return nil
+			},
+			hcl: func(cfg *Config) cty.Value {
+				return cty.NumberIntVal(cfg.ScriptingConfig().TimeLimit)
+			},
+		},
+		{
+			Name:  "engine",
+			Block: []string{"engine"},
+			get: func(cfg *Config) string {
+				return cfg.DefaultEngine().Driver
+			},
+		},
+	}
+)
+
+// Settings returns all the known settings
+func Settings() []*Setting {
+	return settings
+}
+
+// FindSetting fetches the setting with the specified name if exists
+func FindSetting(name string) (*Setting, bool) {
+	name = strings.ToLower(name)
+
+	for _, setting := range settings {
+		if setting.Name == name {
+			return setting, true
+		}
+	}
+
+	return nil, false
+}
+
+// Get returns the value of the setting in the specified configs
+func (s *Setting) Get(cfg *Config) string {
+	return s.get(cfg)
+}
+
+// Set validates and sets the value of the setting in the specified configs
+func (s *Setting) Set(cfg *Config, val string) error {
+	if !s.Mutable {
+		return fmt.Errorf("the '%s' setting can't be changed at runtime", s.Name)
+	}
+
+	if err := s.set(cfg, val); err != nil {
+		return fmt.Errorf("invalid value for '%s' (%s)", s.Name, err.Error())
+	}
+
+	return nil
+}
+
+// SlowlogConfig returns a copy of the slowlog configs filled with the defaults,
+// its threshold is always set as a block without it would log every command otherwise.
+func (cfg *Config) SlowlogConfig() *SlowlogConfig {
+	slowlogCfg := SlowlogConfig{
+		MaxLen: DefaultSlowlogMaxLen,
+	}
+
+	if cfg.Server.Redis.Slowlog != nil {
+		slowlogCfg = *cfg.Server.Redis.Slowlog
+
+		if slowlogCfg.MaxLen < 1 {
+			slowlogCfg.MaxLen = DefaultSlowlogMaxLen
+		}
+	}
+
+	logSlowerThan := int64(DefaultSlowlogLogSlowerThan)
+	if slowlogCfg.LogSlowerThan != nil {
+		logSlowerThan = *slowlogCfg.LogSlowerThan
+	}
+
+	slowlogCfg.LogSlowerThan = &logSlowerThan
+
+	return &slowlogCfg
+}
+
+// ScriptingConfig returns a copy of the scripting configs filled with the defaults
+func (cfg *Config) ScriptingConfig() *ScriptingConfig {
+	scriptingCfg := ScriptingConfig{
+		TimeLimit: DefaultScriptingTimeLimit,
+	}
+
+	if cfg.Server.Redis.Scripting != nil {
+		scriptingCfg = *cfg.Server.Redis.Scripting
+	}
+
+	return &scriptingCfg
+}
+
+// parseInt parses the specified value as an integer not less than min
+func parseInt(val string, min int64) (int64, error) {
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("not an integer")
+	}
+
+	if n < min {
+		return 0, fmt.Errorf("must be greater than or equal to %d", min)
+	}
+
+	return n, nil
+}
+
+// parseBool parses the specified value as a boolean (yes/no are accepted too)
+func parseBool(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "yes", "true", "1":
+		return true, nil
+	case "no", "false", "0":
+		return false, nil
+	}
+
+	return false, fmt.Errorf("not a boolean")
+}
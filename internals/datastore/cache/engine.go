@@ -0,0 +1,361 @@
+// Package cache implements a read-through cache in front of any contract.Engine
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// the supported eviction policies
+const (
+	PolicyLRU = "lru"
+	PolicyLFU = "lfu"
+)
+
+// the default options values
+const (
+	DefaultPolicy  = PolicyLRU
+	DefaultMaxKeys = 10000
+	DefaultTTL     = time.Minute
+	DefaultChannel = "__redix_cache_invalidations__"
+)
+
+// Options represents the cache options
+type Options struct {
+	// Policy the eviction policy used once MaxKeys is reached, "lru" or "lfu"
+	Policy string
+
+	// MaxKeys the maximum number of the cached keys
+	MaxKeys int
+
+	// TTL the maximum duration a key is kept cached, the key own TTL is honoured if it is shorter
+	TTL time.Duration
+
+	// Distributed whether to invalidate the keys cached by the other nodes sharing
+	// the same backend using its publish/subscribe or not
+	Distributed bool
+
+	// Channel the channel the invalidations are published to when Distributed is set
+	Channel string
+}
+
+// Engine wraps a contract.Engine to serve its reads from memory
+type Engine struct {
+	contract.Engine
+
+	opts       Options
+	store      *store
+	nodeID     string
+	subscribed int32
+	closed     chan struct{}
+}
+
+// batchEngine a cached Engine for the engines that support batch writes
+type batchEngine struct {
+	*Engine
+	batchWriter contract.BatchWriter
+}
+
+// invalidation represents an invalidation message exchanged between the nodes
+type invalidation struct {
+	Node   string   `json:"node"`
+	Keys   [][]byte `json:"keys,omitempty"`
+	Prefix []byte   `json:"prefix,omitempty"`
+	All    bool     `json:"all,omitempty"`
+}
+
+// New wraps the specified engine with a cached one,
+// the optional engine interfaces are kept available.
+func New(engine contract.Engine, opts Options) (contract.Engine, error) {
+	if opts.Policy == "" {
+		opts.Policy = DefaultPolicy
+	}
+
+	if opts.MaxKeys < 1 {
+		opts.MaxKeys = DefaultMaxKeys
+	}
+
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+
+	if opts.Channel == "" {
+		opts.Channel = DefaultChannel
+	}
+
+	var p policy
+
+	switch opts.Policy {
+	case PolicyLRU:
+		p = newLRU()
+	case PolicyLFU:
+		p = newLFU()
+	default:
+		return nil, fmt.Errorf("unknown cache policy (%s) specified", opts.Policy)
+	}
+
+	nodeID := make([]byte, 8)
+	if _, err := rand.Read(nodeID); err != nil {
+		return nil, err
+	}
+
+	cached := &Engine{
+		Engine: engine,
+		opts:   opts,
+		store:  newStore(p, opts.MaxKeys, opts.TTL),
+		nodeID: hex.EncodeToString(nodeID),
+		closed: make(chan struct{}),
+	}
+
+	if opts.Distributed {
+		go cached.subscribe()
+	}
+
+	if batchWriter, ok := engine.(contract.BatchWriter); ok {
+		return &batchEngine{Engine: cached, batchWriter: batchWriter}, nil
+	}
+
+	return cached, nil
+}
+
+// Read reads from the cache, it falls back to the underlying engine on misses
+func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	if input == nil || input.Delete {
+		ret, err := e.Engine.Read(input)
+		if input != nil {
+			e.invalidate(&invalidation{Keys: [][]byte{input.Key}})
+		}
+
+		return ret, err
+	}
+
+	// the cache can't be trusted while the other nodes invalidations can't be received
+	if e.opts.Distributed && atomic.LoadInt32(&e.subscribed) == 0 {
+		return e.Engine.Read(input)
+	}
+
+	if ret, found := e.store.get(input.Key); found {
+		return ret, nil
+	}
+
+	generation := e.store.generation(input.Key)
+
+	ret, err := e.Engine.Read(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if ret != nil {
+		e.store.set(input.Key, ret, generation)
+	}
+
+	return ret, nil
+}
+
+// Write writes into the underlying engine then invalidates the affected keys
+func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	ret, err := e.Engine.Write(input)
+
+	if input != nil {
+		e.invalidate(invalidationOf(input))
+	}
+
+	return ret, err
+}
+
+// Stats reports the stats of the underlying engine if it supports that, besides the cache stats
+func (e *Engine) Stats() (map[string]interface{}, error) {
+	stats := map[string]interface{}{}
+
+	if reporter, ok := e.Engine.(contract.Reporter); ok {
+		engineStats, err := reporter.Stats()
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range engineStats {
+			stats[k] = v
+		}
+	}
+
+	keys, hits, misses, evictions := e.store.stats()
+
+	stats["cache_policy"] = e.opts.Policy
+	stats["cache_keys"] = keys
+	stats["cache_max_keys"] = e.opts.MaxKeys
+	stats["cache_hits"] = hits
+	stats["cache_misses"] = misses
+	stats["cache_evictions"] = evictions
+
+	return stats, nil
+}
+
+// Keyspace reports the keyspace of the underlying engine if it supports that
+func (e *Engine) Keyspace() (map[int]*contract.KeyspaceStats, error) {
+	if reporter, ok := e.Engine.(contract.Reporter); ok {
+		return reporter.Keyspace()
+	}
+
+	return nil, nil
+}
+
+// SubscribeContext subscribes using the underlying engine till the context is done
+func (e *Engine) SubscribeContext(ctx context.Context, channel []byte, cb func([]byte) error) error {
+	return contract.SubscribeContext(ctx, e.Engine, channel, cb)
+}
+
+// PSubscribe subscribes to the channels matching the pattern using the underlying engine
+func (e *Engine) PSubscribe(ctx context.Context, pattern []byte, cb func([]byte, []byte) error) error {
+	return contract.PSubscribe(ctx, e.Engine, pattern, cb)
+}
+
+// Close stops receiving the invalidations then closes the underlying engine
+func (e *Engine) Close() error {
+	close(e.closed)
+
+	return e.Engine.Close()
+}
+
+// invalidate applies the specified invalidation locally then broadcasts it to the other nodes
+func (e *Engine) invalidate(inv *invalidation) {
+	e.apply(inv)
+
+	if !e.opts.Distributed {
+		return
+	}
+
+	inv.Node = e.nodeID
+
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		log.Println("[ERROR] (cache) unable to encode the invalidation due to:", err.Error())
+		return
+	}
+
+	if err := e.Engine.Publish([]byte(e.opts.Channel), payload); err != nil {
+		// i.e the payload exceeded the backend limits, so let's invalidate everything instead
+		payload, _ = json.Marshal(&invalidation{Node: e.nodeID, All: true})
+
+		if err := e.Engine.Publish([]byte(e.opts.Channel), payload); err != nil {
+			log.Println("[ERROR] (cache) unable to broadcast the invalidation due to:", err.Error())
+		}
+	}
+}
+
+// apply removes the keys affected by the specified invalidation
+func (e *Engine) apply(inv *invalidation) {
+	switch {
+	case inv.All:
+		e.store.purge()
+	case inv.Prefix != nil:
+		e.store.invalidatePrefix(inv.Prefix)
+	default:
+		e.store.invalidate(inv.Keys...)
+	}
+}
+
+// subscribe receives the invalidations broadcasted by the other nodes till the engine is closed
+func (e *Engine) subscribe() {
+	backoff := time.Second
+
+	for {
+		startedAt := time.Now()
+
+		atomic.StoreInt32(&e.subscribed, 1)
+
+		err := e.Engine.Subscribe([]byte(e.opts.Channel), func(payload []byte) error {
+			var inv invalidation
+
+			if err := json.Unmarshal(payload, &inv); err != nil {
+				log.Println("[ERROR] (cache) unable to decode the invalidation due to:", err.Error())
+				return nil
+			}
+
+			if inv.Node != e.nodeID {
+				e.apply(&inv)
+			}
+
+			return nil
+		})
+
+		// the invalidations sent in the meantime are lost
+		atomic.StoreInt32(&e.subscribed, 0)
+		e.store.purge()
+
+		select {
+		case <-e.closed:
+			return
+		default:
+		}
+
+		log.Println("[ERROR] (cache) the invalidations subscription has been interrupted due to:", err)
+
+		if time.Since(startedAt) > time.Minute {
+			backoff = time.Second
+		}
+
+		select {
+		case <-e.closed:
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff < time.Second*30 {
+			backoff *= 2
+		}
+	}
+}
+
+// invalidationOf returns the invalidation of the keys affected by the specified write
+func invalidationOf(input *contract.WriteInput) *invalidation {
+	if input.Key == nil {
+		return &invalidation{All: true}
+	}
+
+	if input.Value == nil {
+		return &invalidation{Prefix: input.Key}
+	}
+
+	return &invalidation{Keys: [][]byte{input.Key}}
+}
+
+// mergeInvalidations merges the specified invalidations into a single one
+func mergeInvalidations(invs []*invalidation) *invalidation {
+	inv := &invalidation{}
+
+	for _, affected := range invs {
+		switch {
+		case affected.All || affected.Prefix != nil:
+			inv = &invalidation{All: true}
+		case !inv.All:
+			inv.Keys = append(inv.Keys, affected.Keys...)
+		}
+	}
+
+	return inv
+}
+
+// WriteBatch writes the specified inputs into the underlying engine at once then invalidates the affected keys
+func (e *batchEngine) WriteBatch(inputs []*contract.WriteInput) error {
+	err := e.batchWriter.WriteBatch(inputs)
+
+	var affected []*invalidation
+
+	for _, input := range inputs {
+		if input != nil {
+			affected = append(affected, invalidationOf(input))
+		}
+	}
+
+	e.invalidate(mergeInvalidations(affected))
+
+	return err
+}
@@ -0,0 +1,317 @@
+package cache
+
+import (
+	"bytes"
+	"container/heap"
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// generationsCount the number of the invalidation generations the keys are spread over
+const generationsCount = 256
+
+// entry represents a cached read output
+type entry struct {
+	key          string
+	output       contract.ReadOutput
+	expiresAt    time.Time
+	keyExpiresAt time.Time
+	hits         uint64
+	lastUsedAt   time.Time
+
+	// element the position of the entry in the lru list
+	element *list.Element
+
+	// index the position of the entry in the lfu heap
+	index int
+}
+
+// policy represents an eviction policy
+type policy interface {
+	// add starts tracking the specified entry
+	add(*entry)
+
+	// touch records an access to the specified entry
+	touch(*entry)
+
+	// remove stops tracking the specified entry
+	remove(*entry)
+
+	// victim returns the entry that should be evicted first
+	victim() *entry
+
+	// reset stops tracking all of the entries
+	reset()
+}
+
+// store represents a size-bounded set of cached entries
+type store struct {
+	entries     map[string]*entry
+	policy      policy
+	maxKeys     int
+	maxAge      time.Duration
+	generations [generationsCount]uint64
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	sync.Mutex
+}
+
+// newStore creates a new store evicting its entries using the specified policy
+func newStore(policy policy, maxKeys int, maxAge time.Duration) *store {
+	return &store{
+		entries: map[string]*entry{},
+		policy:  policy,
+		maxKeys: maxKeys,
+		maxAge:  maxAge,
+	}
+}
+
+// get returns the cached read output of the specified key if it is still fresh
+func (s *store) get(key []byte) (*contract.ReadOutput, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+
+	e, found := s.entries[string(key)]
+	if found && now.After(e.expiresAt) {
+		s.delete(e)
+		found = false
+	}
+
+	if !found {
+		s.misses++
+		return nil, false
+	}
+
+	s.hits++
+
+	e.hits++
+	e.lastUsedAt = now
+	s.policy.touch(e)
+
+	output := e.output
+	if !e.keyExpiresAt.IsZero() {
+		output.TTL = e.keyExpiresAt.Sub(now)
+	}
+
+	return &output, true
+}
+
+// generation returns the current invalidation generation of the specified key
+func (s *store) generation(key []byte) uint64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.generations[slot(key)]
+}
+
+// set caches the specified read output unless the key has been invalidated
+// since the specified generation has been taken.
+func (s *store) set(key []byte, output *contract.ReadOutput, generation uint64) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.generations[slot(key)] != generation {
+		return
+	}
+
+	now := time.Now()
+
+	e := &entry{
+		key:        string(key),
+		output:     *output,
+		expiresAt:  now.Add(s.maxAge),
+		lastUsedAt: now,
+	}
+
+	if output.Exists && output.TTL > 0 {
+		e.keyExpiresAt = now.Add(output.TTL)
+
+		if e.keyExpiresAt.Before(e.expiresAt) {
+			e.expiresAt = e.keyExpiresAt
+		}
+	}
+
+	if old, found := s.entries[e.key]; found {
+		s.delete(old)
+	}
+
+	for len(s.entries) >= s.maxKeys {
+		s.delete(s.policy.victim())
+		s.evictions++
+	}
+
+	s.entries[e.key] = e
+	s.policy.add(e)
+}
+
+// invalidate removes the specified keys
+func (s *store) invalidate(keys ...[]byte) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, key := range keys {
+		s.generations[slot(key)]++
+
+		if e, found := s.entries[string(key)]; found {
+			s.delete(e)
+		}
+	}
+}
+
+// invalidatePrefix removes the keys having the specified prefix
+func (s *store) invalidatePrefix(prefix []byte) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.bump()
+
+	for k, e := range s.entries {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			s.delete(e)
+		}
+	}
+}
+
+// purge removes all of the keys
+func (s *store) purge() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.bump()
+
+	s.entries = map[string]*entry{}
+	s.policy.reset()
+}
+
+// stats returns the cache counters
+func (s *store) stats() (keys int, hits, misses, evictions uint64) {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.entries), s.hits, s.misses, s.evictions
+}
+
+// delete removes the specified entry, the lock must be held by the caller
+func (s *store) delete(e *entry) {
+	delete(s.entries, e.key)
+	s.policy.remove(e)
+}
+
+// bump invalidates all of the generations, the lock must be held by the caller
+func (s *store) bump() {
+	for i := range s.generations {
+		s.generations[i]++
+	}
+}
+
+// slot returns the invalidation generation index of the specified key
+func slot(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+
+	return int(h.Sum32() % generationsCount)
+}
+
+// lru evicts the least recently used entry first
+type lru struct {
+	entries *list.List
+}
+
+func newLRU() *lru {
+	return &lru{entries: list.New()}
+}
+
+func (p *lru) add(e *entry) {
+	e.element = p.entries.PushFront(e)
+}
+
+func (p *lru) touch(e *entry) {
+	p.entries.MoveToFront(e.element)
+}
+
+func (p *lru) remove(e *entry) {
+	p.entries.Remove(e.element)
+}
+
+func (p *lru) victim() *entry {
+	return p.entries.Back().Value.(*entry)
+}
+
+func (p *lru) reset() {
+	p.entries.Init()
+}
+
+// lfu evicts the least frequently used entry first,
+// the least recently used one is evicted among the equally used entries.
+type lfu struct {
+	entries lfuHeap
+}
+
+func newLFU() *lfu {
+	return &lfu{}
+}
+
+func (p *lfu) add(e *entry) {
+	heap.Push(&p.entries, e)
+}
+
+func (p *lfu) touch(e *entry) {
+	heap.Fix(&p.entries, e.index)
+}
+
+func (p *lfu) remove(e *entry) {
+	heap.Remove(&p.entries, e.index)
+}
+
+func (p *lfu) victim() *entry {
+	return p.entries[0]
+}
+
+func (p *lfu) reset() {
+	p.entries = nil
+}
+
+// lfuHeap implements heap.Interface ordering the entries by their usage
+type lfuHeap []*entry
+
+func (h lfuHeap) Len() int {
+	return len(h)
+}
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].hits != h[j].hits {
+		return h[i].hits < h[j].hits
+	}
+
+	return h[i].lastUsedAt.Before(h[j].lastUsedAt)
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return e
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// transaction wraps a contract.Transaction to invalidate the keys it affected once committed,
+// its reads bypass the cache as they may see its uncommitted writes.
+type transaction struct {
+	contract.Transaction
+
+	engine   *Engine
+	affected []*invalidation
+	sync.Mutex
+}
+
+// Begin starts a new transaction on the underlying engine if it supports that
+func (e *Engine) Begin() (contract.Transaction, error) {
+	transactor, ok := e.Engine.(contract.Transactor)
+	if !ok {
+		return nil, contract.ErrTransactionUnsupported
+	}
+
+	tx, err := transactor.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{Transaction: tx, engine: e}, nil
+}
+
+// Write writes into the underlying transaction then records the affected keys
+func (t *transaction) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	ret, err := t.Transaction.Write(input)
+
+	if input != nil {
+		t.record(invalidationOf(input))
+	}
+
+	return ret, err
+}
+
+// Read reads from the underlying transaction, the deleted keys are recorded
+func (t *transaction) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	ret, err := t.Transaction.Read(input)
+
+	if input != nil && input.Delete {
+		t.record(&invalidation{Keys: [][]byte{input.Key}})
+	}
+
+	return ret, err
+}
+
+// Commit commits the underlying transaction then invalidates the affected keys
+func (t *transaction) Commit() error {
+	err := t.Transaction.Commit()
+
+	t.Lock()
+	defer t.Unlock()
+
+	if len(t.affected) > 0 {
+		t.engine.invalidate(mergeInvalidations(t.affected))
+	}
+
+	return err
+}
+
+// record records the specified affected keys
+func (t *transaction) record(inv *invalidation) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.affected = append(t.affected, inv)
+}
@@ -1,6 +1,7 @@
 package contract
 
 import (
+	"context"
 	"errors"
 	"time"
 )
@@ -16,6 +17,70 @@ type Engine interface {
 	Subscribe([]byte, func([]byte) error) error
 }
 
+// Reporter represents an optional interface an Engine may implement to report its own stats
+type Reporter interface {
+	// Stats returns the backend specific stats
+	Stats() (map[string]interface{}, error)
+
+	// Keyspace returns the keys stats grouped by the db index
+	Keyspace() (map[int]*KeyspaceStats, error)
+}
+
+// BatchWriter represents an optional interface an Engine may implement to apply multiple writes at once
+type BatchWriter interface {
+	// WriteBatch applies all the specified writes or none of them,
+	// ErrBatchUnsupported is returned if the specified writes can't be applied at once.
+	WriteBatch([]*WriteInput) error
+}
+
+// Rebalancer represents an optional interface an Engine may implement to move the keys stored in the wrong place
+type Rebalancer interface {
+	// Rebalance moves the misplaced keys to where they belong, it returns the number of the moved keys
+	Rebalance() (int64, error)
+}
+
+// ReadReplicator represents an optional interface an Engine may implement to serve the reads from a read-only replica
+type ReadReplicator interface {
+	// OpenReadReplica connects to the replica using the specified dsn, it must be called after Open
+	OpenReadReplica(string) error
+}
+
+// Transactor represents an optional interface an Engine may implement to apply many operations atomically
+type Transactor interface {
+	// Begin starts a new transaction, ErrTransactionUnsupported is returned if the engine can't start one
+	Begin() (Transaction, error)
+}
+
+// Transaction represents an Engine whose writes are only visible to the others once committed
+type Transaction interface {
+	Engine
+
+	// Commit applies the transaction writes
+	Commit() error
+
+	// Rollback discards the transaction writes
+	Rollback() error
+}
+
+// ContextSubscriber represents an optional interface an Engine may implement to end the subscriptions on demand
+type ContextSubscriber interface {
+	// SubscribeContext is the same as Subscribe, but it returns ctx.Err() once the specified context is done
+	SubscribeContext(ctx context.Context, channel []byte, cb func([]byte) error) error
+}
+
+// PatternSubscriber represents an optional interface an Engine may implement to subscribe to many channels at once
+type PatternSubscriber interface {
+	// PSubscribe listens for the incoming payloads on the channels matching the specified pattern (as path.Match),
+	// the callback receives the channel of each payload, it returns ctx.Err() once the specified context is done.
+	PSubscribe(ctx context.Context, pattern []byte, cb func(channel []byte, payload []byte) error) error
+}
+
+// KeyspaceStats represents the keys stats of a db index
+type KeyspaceStats struct {
+	Keys    int64
+	Expires int64
+}
+
 // WriteInput represents a PUT request
 type WriteInput struct {
 	Key             []byte
@@ -35,7 +100,9 @@ type WriteOutput struct {
 
 // ReadInput represents a Get request
 type ReadInput struct {
-	Key    []byte
+	Key []byte
+
+	// Delete whether to delete the exact key (not the keys prefixed by it) once read
 	Delete bool
 }
 
@@ -55,5 +122,9 @@ type IteratorOpts struct {
 
 // global vars
 var (
-	ErrStopIterator = errors.New("STOP_ITERATOR")
+	ErrStopIterator           = errors.New("STOP_ITERATOR")
+	ErrBatchUnsupported       = errors.New("BATCH_UNSUPPORTED")
+	ErrTransactionUnsupported = errors.New("TRANSACTION_UNSUPPORTED")
+	ErrTransactionConflict    = errors.New("TRANSACTION_CONFLICT")
+	ErrPatternsUnsupported    = errors.New("PATTERNS_UNSUPPORTED")
 )
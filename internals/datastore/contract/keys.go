@@ -0,0 +1,25 @@
+package contract
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// SplitKey splits the specified absolute key ("/<db>/<key>") into its db index and the key relative to that db
+func SplitKey(key []byte) (int, []byte, bool) {
+	if len(key) < 1 || key[0] != '/' {
+		return 0, nil, false
+	}
+
+	end := bytes.IndexByte(key[1:], '/')
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	db, err := strconv.Atoi(string(key[1 : end+1]))
+	if err != nil {
+		return 0, nil, false
+	}
+
+	return db, key[end+2:], true
+}
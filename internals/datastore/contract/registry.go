@@ -6,12 +6,12 @@ import (
 )
 
 var (
-	engines     = map[string]Engine{}
+	engines     = map[string]func() Engine{}
 	enginesLock = new(sync.RWMutex)
 )
 
-// Register adds the specified engine to the registry
-func Register(name string, engine Engine) {
+// Register adds the specified engine factory to the registry
+func Register(name string, factory func() Engine) {
 	enginesLock.Lock()
 	defer enginesLock.Unlock()
 
@@ -19,19 +19,21 @@ func Register(name string, engine Engine) {
 		panic(fmt.Errorf("duplicate driver name (%s)", name))
 	}
 
-	engines[name] = engine
+	engines[name] = factory
 }
 
-// Open initialize an instance of the specified engine name + dsn
+// Open initialize a new instance of the specified engine name + dsn
 func Open(name string, dsn string) (Engine, error) {
 	enginesLock.RLock()
-	defer enginesLock.RUnlock()
+	factory, exists := engines[name]
+	enginesLock.RUnlock()
 
-	engine, exists := engines[name]
 	if !exists {
 		return nil, fmt.Errorf("unknown driver name (%s) specified", name)
 	}
 
+	engine := factory()
+
 	return engine, engine.Open(dsn)
 }
 
@@ -0,0 +1,61 @@
+package contract
+
+// CombinedStats reports the stats of each of the specified engines prefixed by its name,
+// besides the expired keys of all of them combined, the engines that aren't reporters are skipped.
+func CombinedStats(names []string, engines map[string]Engine) (map[string]interface{}, error) {
+	stats := map[string]interface{}{}
+	expiredKeys := int64(0)
+
+	for _, name := range names {
+		reporter, ok := engines[name].(Reporter)
+		if !ok {
+			continue
+		}
+
+		engineStats, err := reporter.Stats()
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range engineStats {
+			stats[name+"_"+k] = v
+		}
+
+		if n, ok := engineStats["expired_keys"].(int64); ok {
+			expiredKeys += n
+		}
+	}
+
+	stats["expired_keys"] = expiredKeys
+
+	return stats, nil
+}
+
+// CombinedKeyspace reports the keyspace of all of the specified engines combined,
+// the engines that aren't reporters are skipped.
+func CombinedKeyspace(names []string, engines map[string]Engine) (map[int]*KeyspaceStats, error) {
+	result := map[int]*KeyspaceStats{}
+
+	for _, name := range names {
+		reporter, ok := engines[name].(Reporter)
+		if !ok {
+			continue
+		}
+
+		keyspace, err := reporter.Keyspace()
+		if err != nil {
+			return nil, err
+		}
+
+		for db, stats := range keyspace {
+			if result[db] == nil {
+				result[db] = &KeyspaceStats{}
+			}
+
+			result[db].Keys += stats.Keys
+			result[db].Expires += stats.Expires
+		}
+	}
+
+	return result, nil
+}
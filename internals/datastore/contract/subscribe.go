@@ -0,0 +1,32 @@
+package contract
+
+import (
+	"context"
+)
+
+// SubscribeContext subscribes to the specified channel till the callback fails or the context is done,
+// the engines that don't implement ContextSubscriber only notice the context once the next payload is received.
+func SubscribeContext(ctx context.Context, engine Engine, channel []byte, cb func([]byte) error) error {
+	if subscriber, ok := engine.(ContextSubscriber); ok {
+		return subscriber.SubscribeContext(ctx, channel, cb)
+	}
+
+	return engine.Subscribe(channel, func(payload []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		return cb(payload)
+	})
+}
+
+// PSubscribe subscribes to the channels matching the specified pattern till the callback fails or the context is done,
+// ErrPatternsUnsupported is returned if the engine doesn't implement PatternSubscriber.
+func PSubscribe(ctx context.Context, engine Engine, pattern []byte, cb func([]byte, []byte) error) error {
+	subscriber, ok := engine.(PatternSubscriber)
+	if !ok {
+		return ErrPatternsUnsupported
+	}
+
+	return subscriber.PSubscribe(ctx, pattern, cb)
+}
@@ -81,7 +81,7 @@ func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error
 		return nil, nil
 	}
 
-	if err := os.MkdirAll(filepath.Join(e.kvDir, "/kv"), 0775); err != nil && err != os.ErrExist {
+	if err := os.MkdirAll(e.kvDir, 0775); err != nil && err != os.ErrExist {
 		return nil, err
 	}
 
@@ -105,32 +105,18 @@ func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
 
 	data, err := ReadFileWithSharedLock(keyDataPath)
 	if err != nil {
-		if err == os.ErrNotExist {
+		if os.IsNotExist(err) {
 			return &contract.ReadOutput{}, nil
 		}
 
 		return nil, err
 	}
 
+	// the deleting reads remove the exact key only, like the other engines
 	if input.Delete {
-		return nil, filepath.WalkDir(e.kvDir, func(path string, d fs.DirEntry, err error) error {
-			if path == e.kvDir {
-				return nil
-			}
-
-			actualKey, err := hex.DecodeString(d.Name())
-			if err != nil {
-				return err
-			}
-
-			if bytes.HasPrefix(actualKey, input.Key) {
-				if err := os.Remove(path); err != nil {
-					return err
-				}
-			}
-
-			return nil
-		})
+		if err := os.Remove(keyDataPath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
 	}
 
 	return &contract.ReadOutput{
@@ -156,6 +142,10 @@ func (e *Engine) Iterate(opts *contract.IteratorOpts) error {
 			return nil
 		}
 
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+
 		actualKey, err := hex.DecodeString(d.Name())
 		if err != nil {
 			return err
@@ -10,5 +10,7 @@ const (
 )
 
 func init() {
-	contract.Register(Name, &Engine{})
+	contract.Register(Name, func() contract.Engine {
+		return &Engine{}
+	})
 }
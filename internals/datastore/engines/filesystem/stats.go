@@ -0,0 +1,89 @@
+//go:build linux || darwin
+
+package filesystem
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// Stats reports the storage stats
+func (e *Engine) Stats() (map[string]interface{}, error) {
+	keys, size := int64(0), int64(0)
+
+	err := filepath.WalkDir(e.kvDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		keys++
+		size += info.Size()
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"storage_dir":     e.storageDir,
+		"keys":            keys,
+		"data_size_bytes": size,
+	}, nil
+}
+
+// Keyspace reports the number of keys per db index
+func (e *Engine) Keyspace() (map[int]*contract.KeyspaceStats, error) {
+	result := map[int]*contract.KeyspaceStats{}
+
+	err := filepath.WalkDir(e.kvDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		key, err := hex.DecodeString(d.Name())
+		if err != nil {
+			return nil
+		}
+
+		// keys are stored as "/<db index>/<key>"
+		parts := bytes.SplitN(key, []byte("/"), 3)
+		if len(parts) < 3 || len(parts[0]) > 0 {
+			return nil
+		}
+
+		db, err := strconv.Atoi(string(parts[1]))
+		if err != nil {
+			return nil
+		}
+
+		if _, exists := result[db]; !exists {
+			result[db] = &contract.KeyspaceStats{}
+		}
+
+		result[db].Keys++
+
+		return nil
+	})
+
+	return result, err
+}
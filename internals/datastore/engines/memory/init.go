@@ -0,0 +1,14 @@
+package memory
+
+import "github.com/alash3al/redix/internals/datastore/contract"
+
+// Global consts
+const (
+	Name = "memory"
+)
+
+func init() {
+	contract.Register(Name, func() contract.Engine {
+		return &Engine{}
+	})
+}
@@ -0,0 +1,424 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// item represents a stored value
+type item struct {
+	value     []byte
+	expiresAt int64
+}
+
+// expired whether the item has been expired at the specified time or not
+func (i *item) expired(now int64) bool {
+	return i.expiresAt != 0 && i.expiresAt <= now
+}
+
+// ttl returns the remaining time to live of the item, zero if it never expires
+func (i *item) ttl(now int64) time.Duration {
+	if i.expiresAt == 0 {
+		return 0
+	}
+
+	return time.Duration(i.expiresAt - now)
+}
+
+// message represents a payload published to a channel
+type message struct {
+	channel []byte
+	payload []byte
+}
+
+// Engine represents the contract.Engine implementation,
+// the data lives in the process memory and is lost on restart.
+type Engine struct {
+	data        map[string]*item
+	subscribers map[string]map[chan []byte]struct{}
+	patterns    map[string]map[chan *message]struct{}
+	expiredKeys int64
+	closed      chan struct{}
+
+	sync.RWMutex
+}
+
+// Open initializes the storage, the dsn isn't used
+func (e *Engine) Open(dsn string) error {
+	e.data = map[string]*item{}
+	e.subscribers = map[string]map[chan []byte]struct{}{}
+	e.patterns = map[string]map[chan *message]struct{}{}
+	e.closed = make(chan struct{})
+
+	go (func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.closed:
+				return
+			case <-ticker.C:
+				e.deleteExpired()
+			}
+		}
+	})()
+
+	return nil
+}
+
+// Write writes into the database
+func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	if input == nil {
+		return nil, fmt.Errorf("empty input specified")
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	if input.Key == nil {
+		e.data = map[string]*item{}
+		return nil, nil
+	}
+
+	if input.Value == nil {
+		for k := range e.data {
+			if bytes.HasPrefix([]byte(k), input.Key) {
+				delete(e.data, k)
+			}
+		}
+
+		return nil, nil
+	}
+
+	now := time.Now().UnixNano()
+	key := string(input.Key)
+
+	expiresAt := int64(0)
+	if input.TTL > 0 {
+		expiresAt = now + int64(input.TTL)
+	}
+
+	current, exists := e.data[key]
+	if exists && current.expired(now) {
+		delete(e.data, key)
+		exists = false
+	}
+
+	if exists && input.OnlyIfNotExists {
+		return nil, nil
+	}
+
+	value := append([]byte(nil), input.Value...)
+
+	if exists {
+		if input.KeepTTL {
+			expiresAt = current.expiresAt
+		}
+
+		if input.Increment {
+			sum, err := add(current.value, input.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			value = sum
+		} else if input.Append {
+			value = append(append([]byte(nil), current.value...), input.Value...)
+		}
+	} else if input.Increment {
+		if _, err := strconv.ParseFloat(string(input.Value), 64); err != nil {
+			return nil, fmt.Errorf("the specified value is not a number")
+		}
+	}
+
+	stored := &item{value: value, expiresAt: expiresAt}
+	e.data[key] = stored
+
+	return &contract.WriteOutput{
+		Value: stored.value,
+		TTL:   stored.ttl(now),
+	}, nil
+}
+
+// Read reads from the database
+func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	if input == nil {
+		return nil, fmt.Errorf("empty input specified")
+	}
+
+	if input.Delete {
+		e.Lock()
+		defer e.Unlock()
+	} else {
+		e.RLock()
+		defer e.RUnlock()
+	}
+
+	now := time.Now().UnixNano()
+
+	stored, exists := e.data[string(input.Key)]
+	if !exists || stored.expired(now) {
+		return &contract.ReadOutput{}, nil
+	}
+
+	if input.Delete {
+		delete(e.data, string(input.Key))
+	}
+
+	return &contract.ReadOutput{
+		Key:    input.Key,
+		Value:  stored.value,
+		Exists: true,
+		TTL:    stored.ttl(now),
+	}, nil
+}
+
+// Iterate iterates over the keys having the specified prefix in order, stops if the callback returns an error
+func (e *Engine) Iterate(opts *contract.IteratorOpts) error {
+	if opts == nil {
+		return fmt.Errorf("empty options specified")
+	}
+
+	if opts.Callback == nil {
+		return fmt.Errorf("you must specify the callback")
+	}
+
+	now := time.Now().UnixNano()
+
+	var result []*contract.ReadOutput
+
+	// the callback is called without holding the lock, so it may write
+	e.RLock()
+	for k, stored := range e.data {
+		if !bytes.HasPrefix([]byte(k), opts.Prefix) || stored.expired(now) {
+			continue
+		}
+
+		result = append(result, &contract.ReadOutput{
+			Key:    []byte(k),
+			Value:  stored.value,
+			Exists: true,
+			TTL:    stored.ttl(now),
+		})
+	}
+	e.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Key, result[j].Key) < 0
+	})
+
+	for _, ro := range result {
+		if err := opts.Callback(ro); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close stops the active subscriptions then drops the data
+func (e *Engine) Close() error {
+	e.Lock()
+	defer e.Unlock()
+
+	close(e.closed)
+	e.data = map[string]*item{}
+
+	return nil
+}
+
+// Publish submits the payload to the subscribers of the specified channel within the current process
+func (e *Engine) Publish(channel []byte, payload []byte) error {
+	e.RLock()
+	defer e.RUnlock()
+
+	for subscriber := range e.subscribers[string(channel)] {
+		select {
+		case subscriber <- payload:
+		default:
+			// the subscriber is too slow, so it misses the payload instead of blocking the publishers
+		}
+	}
+
+	for pattern, subscribers := range e.patterns {
+		if ok, _ := path.Match(pattern, string(channel)); !ok {
+			continue
+		}
+
+		msg := &message{channel: append([]byte{}, channel...), payload: payload}
+
+		for subscriber := range subscribers {
+			select {
+			case subscriber <- msg:
+			default:
+			}
+		}
+	}
+
+	return nil
+}
+
+// Subscribe listens for the incoming payloads on the specified channel
+func (e *Engine) Subscribe(channel []byte, cb func([]byte) error) error {
+	return e.SubscribeContext(context.Background(), channel, cb)
+}
+
+// SubscribeContext listens for the incoming payloads on the specified channel till the context is done
+func (e *Engine) SubscribeContext(ctx context.Context, channel []byte, cb func([]byte) error) error {
+	if cb == nil {
+		return fmt.Errorf("you must specify a callback (cb)")
+	}
+
+	subscriber := make(chan []byte, 1024)
+
+	e.Lock()
+	if e.subscribers[string(channel)] == nil {
+		e.subscribers[string(channel)] = map[chan []byte]struct{}{}
+	}
+	e.subscribers[string(channel)][subscriber] = struct{}{}
+	e.Unlock()
+
+	defer (func() {
+		e.Lock()
+		delete(e.subscribers[string(channel)], subscriber)
+		if len(e.subscribers[string(channel)]) < 1 {
+			delete(e.subscribers, string(channel))
+		}
+		e.Unlock()
+	})()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.closed:
+			return fmt.Errorf("the %s engine has been closed", Name)
+		case payload := <-subscriber:
+			if err := cb(payload); err != nil {
+				return fmt.Errorf("unable to process notification due to: %s", err.Error())
+			}
+		}
+	}
+}
+
+// PSubscribe listens for the incoming payloads on the channels matching the specified pattern till the context is done
+func (e *Engine) PSubscribe(ctx context.Context, pattern []byte, cb func([]byte, []byte) error) error {
+	if cb == nil {
+		return fmt.Errorf("you must specify a callback (cb)")
+	}
+
+	if _, err := path.Match(string(pattern), ""); err != nil {
+		return err
+	}
+
+	subscriber := make(chan *message, 1024)
+
+	e.Lock()
+	if e.patterns[string(pattern)] == nil {
+		e.patterns[string(pattern)] = map[chan *message]struct{}{}
+	}
+	e.patterns[string(pattern)][subscriber] = struct{}{}
+	e.Unlock()
+
+	defer (func() {
+		e.Lock()
+		delete(e.patterns[string(pattern)], subscriber)
+		if len(e.patterns[string(pattern)]) < 1 {
+			delete(e.patterns, string(pattern))
+		}
+		e.Unlock()
+	})()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.closed:
+			return fmt.Errorf("the %s engine has been closed", Name)
+		case msg := <-subscriber:
+			if err := cb(msg.channel, msg.payload); err != nil {
+				return fmt.Errorf("unable to process notification due to: %s", err.Error())
+			}
+		}
+	}
+}
+
+// Stats reports the storage stats
+func (e *Engine) Stats() (map[string]interface{}, error) {
+	e.RLock()
+	defer e.RUnlock()
+
+	return map[string]interface{}{
+		"keys":         len(e.data),
+		"channels":     len(e.subscribers),
+		"patterns":     len(e.patterns),
+		"expired_keys": atomic.LoadInt64(&e.expiredKeys),
+	}, nil
+}
+
+// Keyspace reports the number of keys per db index
+func (e *Engine) Keyspace() (map[int]*contract.KeyspaceStats, error) {
+	e.RLock()
+	defer e.RUnlock()
+
+	result := map[int]*contract.KeyspaceStats{}
+
+	for k, stored := range e.data {
+		db, _, ok := contract.SplitKey([]byte(k))
+		if !ok {
+			continue
+		}
+
+		if result[db] == nil {
+			result[db] = &contract.KeyspaceStats{}
+		}
+
+		result[db].Keys++
+
+		if stored.expiresAt != 0 {
+			result[db].Expires++
+		}
+	}
+
+	return result, nil
+}
+
+// deleteExpired removes the expired keys
+func (e *Engine) deleteExpired() {
+	e.Lock()
+	defer e.Unlock()
+
+	now := time.Now().UnixNano()
+
+	for k, stored := range e.data {
+		if stored.expired(now) {
+			delete(e.data, k)
+			atomic.AddInt64(&e.expiredKeys, 1)
+		}
+	}
+}
+
+// add sums the specified numbers
+func add(a, b []byte) ([]byte, error) {
+	x, err := strconv.ParseFloat(string(a), 64)
+	if err != nil {
+		return nil, fmt.Errorf("the stored value is not a number")
+	}
+
+	y, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return nil, fmt.Errorf("the specified value is not a number")
+	}
+
+	return []byte(strconv.FormatFloat(x+y, 'f', -1, 64)), nil
+}
@@ -8,5 +8,7 @@ const (
 )
 
 func init() {
-	contract.Register(Name, &Engine{})
+	contract.Register(Name, func() contract.Engine {
+		return &Engine{}
+	})
 }
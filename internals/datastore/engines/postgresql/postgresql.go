@@ -3,20 +3,38 @@ package postgresql
 import (
 	"context"
 	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"path"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+// the pub/sub settings
+const (
+	// patternsChannel the channel every payload is also published to for the pattern subscribers,
+	// as "<hex encoded channel>:<payload>".
+	patternsChannel = "redix_patterns"
+
+	// maxNotifyPayload the longest payload a notification may carry
+	maxNotifyPayload = 7999
+)
+
 // Engine represents the contract.Engine implementation
 type Engine struct {
-	conn *pgxpool.Pool
+	conn        *pgxpool.Pool
+	readConn    *pgxpool.Pool
+	expiredKeys int64
+	ctx         context.Context
+	cancel      context.CancelFunc
 }
 
 // Open opens the database
@@ -26,6 +44,19 @@ func (e *Engine) Open(dsn string) (err error) {
 		return err
 	}
 
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+
+	var inRecovery bool
+
+	if err := e.conn.QueryRow(context.Background(), "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return err
+	}
+
+	// a standby can't be written, so the schema and the expired keys are left to its primary
+	if inRecovery {
+		return nil
+	}
+
 	if _, err := e.conn.Exec(
 		context.Background(),
 		`
@@ -52,29 +83,85 @@ func (e *Engine) Open(dsn string) (err error) {
 		for {
 			now := time.Now().UnixNano()
 
-			if _, err := e.conn.Exec(
-				context.Background(),
+			tag, err := e.conn.Exec(
+				e.ctx,
 				`DELETE FROM redix_data_v5 WHERE _expires_at != 0 and _expires_at <= $1`,
 				now,
-			); err != nil {
+			)
+			if e.ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
 				panic(err)
 			}
 
-			time.Sleep(time.Second * 1)
+			atomic.AddInt64(&e.expiredKeys, tag.RowsAffected())
+
+			select {
+			case <-e.ctx.Done():
+				return
+			case <-time.After(time.Second * 1):
+			}
 		}
 	})()
 
 	return nil
 }
 
+// OpenReadReplica connects to the read-only replica the reads are served from
+func (e *Engine) OpenReadReplica(dsn string) (err error) {
+	e.readConn, err = pgxpool.Connect(context.Background(), dsn)
+
+	return err
+}
+
+// reader returns the connection pool the reads are served from
+func (e *Engine) reader() *pgxpool.Pool {
+	if e.readConn != nil {
+		return e.readConn
+	}
+
+	return e.conn
+}
+
+// querier is the common interface of the connection pool and the transactions
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
 // Write writes into the database
 func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	return e.write(e.conn, input)
+}
+
+// WriteBatch writes all the specified inputs into the database in a single transaction
+func (e *Engine) WriteBatch(inputs []*contract.WriteInput) error {
+	tx, err := e.conn.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	for _, input := range inputs {
+		if _, err := e.write(tx, input); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// write writes the specified input using the specified querier
+func (e *Engine) write(q querier, input *contract.WriteInput) (*contract.WriteOutput, error) {
 	if input == nil {
 		return nil, fmt.Errorf("empty input specified")
 	}
 
 	if input.Key == nil {
-		if _, err := e.conn.Exec(context.Background(), "DELETE FROM redix_data_v5"); err != nil {
+		if _, err := q.Exec(context.Background(), "DELETE FROM redix_data_v5"); err != nil {
 			return nil, err
 		}
 
@@ -82,7 +169,7 @@ func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error
 	}
 
 	if input.Value == nil {
-		if _, err := e.conn.Exec(context.Background(), "DELETE FROM redix_data_v5 WHERE _key LIKE $1", append(input.Key, '%')); err != nil {
+		if _, err := q.Exec(context.Background(), "DELETE FROM redix_data_v5 WHERE _key LIKE $1", append(input.Key, '%')); err != nil {
 			return nil, err
 		}
 
@@ -136,7 +223,7 @@ func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error
 		return nil, err
 	}
 
-	if err := e.conn.QueryRow(
+	if err := q.QueryRow(
 		context.Background(),
 		strings.Join(insertQuery, " "),
 		input.Key, string(jsonVal), ttl,
@@ -159,14 +246,49 @@ func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
 		return nil, fmt.Errorf("empty input specified")
 	}
 
+	// the deleting reads must see the latest value, which the replica may not have received yet
+	q := e.reader()
+	if input.Delete {
+		q = e.conn
+	}
+
+	readOutput, err := e.read(q, input.Key)
+	if err != nil || !readOutput.Exists {
+		return readOutput, err
+	}
+
+	deleter := func() {
+		// TODO report any expected error?
+		e.conn.Exec(context.Background(), "DELETE FROM redix_data_v5 WHERE _key = $1", input.Key)
+	}
+
+	if readOutput.TTL < 0 {
+		go (func() {
+			deleter()
+			atomic.AddInt64(&e.expiredKeys, 1)
+		})()
+		return &contract.ReadOutput{}, nil
+	}
+
+	if input.Delete {
+		go (func() {
+			deleter()
+		})()
+	}
+
+	return readOutput, nil
+}
+
+// read reads the specified key using the specified querier, the expired keys are returned with a negative TTL
+func (e *Engine) read(q querier, key []byte) (*contract.ReadOutput, error) {
 	var retQueryVal []byte
 	var retVal interface{}
 	var retExpiresAt int64
 
-	if err := e.conn.QueryRow(
+	if err := q.QueryRow(
 		context.Background(),
 		"SELECT _value, _expires_at FROM redix_data_v5 WHERE _key = $1",
-		input.Key,
+		key,
 	).Scan(&retQueryVal, &retExpiresAt); err != nil {
 		if err == pgx.ErrNoRows {
 			return &contract.ReadOutput{}, nil
@@ -180,7 +302,7 @@ func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
 	}
 
 	readOutput := contract.ReadOutput{
-		Key:    input.Key,
+		Key:    key,
 		Value:  []byte(fmt.Sprintf("%v", retVal)),
 		TTL:    0,
 		Exists: true,
@@ -190,29 +312,16 @@ func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
 		readOutput.TTL = time.Unix(0, retExpiresAt).Sub(time.Now())
 	}
 
-	deleter := func() {
-		// TODO report any expected error?
-		e.conn.Exec(context.Background(), "DELETE FROM redix_data_v5 WHERE _key = $1", input.Key)
-	}
-
-	if readOutput.TTL < 0 {
-		go (func() {
-			deleter()
-		})()
-		return &contract.ReadOutput{}, nil
-	}
-
-	if input.Delete {
-		go (func() {
-			deleter()
-		})()
-	}
-
 	return &readOutput, nil
 }
 
 // Iterate iterates on the whole database stops if the IteratorOpts returns an error
 func (e *Engine) Iterate(opts *contract.IteratorOpts) error {
+	return e.iterate(e.reader(), opts)
+}
+
+// iterate iterates on the whole database using the specified querier
+func (e *Engine) iterate(q querier, opts *contract.IteratorOpts) error {
 	if opts == nil {
 		return fmt.Errorf("empty options specified")
 	}
@@ -221,7 +330,7 @@ func (e *Engine) Iterate(opts *contract.IteratorOpts) error {
 		return fmt.Errorf("you must specify the callback")
 	}
 
-	iter, err := e.conn.Query(context.Background(), "SELECT _key, _value, _expires_at FROM redix_data_v5 WHERE _key LIKE $1 ORDER BY _id ASC", append(opts.Prefix, '%'))
+	iter, err := q.Query(context.Background(), "SELECT _key, _value, _expires_at FROM redix_data_v5 WHERE _key LIKE $1 ORDER BY _id ASC", append(opts.Prefix, '%'))
 	if err != nil {
 		return err
 	}
@@ -264,45 +373,118 @@ func (e *Engine) Iterate(opts *contract.IteratorOpts) error {
 	return iter.Err()
 }
 
-// Close closes the connection
+// Close cancels the active subscriptions then closes the connection
 func (e *Engine) Close() error {
+	e.cancel()
 	e.conn.Close()
+
+	if e.readConn != nil {
+		e.readConn.Close()
+	}
+
 	return nil
 }
 
-// Publish submits the payload to the specified channel
+// Publish submits the payload to the specified channel, it is also submitted to the pattern subscribers
+// unless it exceeds the notifications limit once the channel is prepended to it.
 func (e *Engine) Publish(channel []byte, payload []byte) error {
 	channelEncoded := fmt.Sprintf("%x", md5.Sum(channel))
-	if _, err := e.conn.Exec(context.Background(), "SELECT pg_notify($1, $2)", channelEncoded, payload); err != nil {
+
+	patternPayload := hex.EncodeToString(channel) + ":" + string(payload)
+	if len(patternPayload) > maxNotifyPayload {
+		_, err := e.conn.Exec(context.Background(), "SELECT pg_notify($1, $2)", channelEncoded, payload)
 		return err
 	}
 
-	return nil
+	_, err := e.conn.Exec(context.Background(), "SELECT pg_notify($1, $2), pg_notify($3, $4)", channelEncoded, payload, patternsChannel, patternPayload)
+
+	return err
 }
 
 // Subscribe listens for the incoming payloads on the specified channel
 func (e *Engine) Subscribe(channel []byte, cb func([]byte) error) error {
+	return e.SubscribeContext(context.Background(), channel, cb)
+}
+
+// SubscribeContext listens for the incoming payloads on the specified channel till the context is done
+func (e *Engine) SubscribeContext(ctx context.Context, channel []byte, cb func([]byte) error) error {
+	if cb == nil {
+		return fmt.Errorf("you must specify a callback (cb)")
+	}
+
+	return e.listen(ctx, fmt.Sprintf("%x", md5.Sum(channel)), func(payload string) error {
+		return cb([]byte(payload))
+	})
+}
+
+// PSubscribe listens for the incoming payloads on the channels matching the specified pattern till the context is done
+func (e *Engine) PSubscribe(ctx context.Context, pattern []byte, cb func([]byte, []byte) error) error {
 	if cb == nil {
 		return fmt.Errorf("you must specify a callback (cb)")
 	}
 
-	conn, err := e.conn.Acquire(context.Background())
+	if _, err := path.Match(string(pattern), ""); err != nil {
+		return err
+	}
+
+	return e.listen(ctx, patternsChannel, func(payload string) error {
+		sep := strings.IndexByte(payload, ':')
+		if sep < 0 {
+			return nil
+		}
+
+		channel, err := hex.DecodeString(payload[:sep])
+		if err != nil {
+			return nil
+		}
+
+		if ok, _ := path.Match(string(pattern), string(channel)); !ok {
+			return nil
+		}
+
+		return cb(channel, []byte(payload[sep+1:]))
+	})
+}
+
+// listen listens for the notifications of the specified channel on a dedicated connection
+// till the callback fails, the specified context is done or the engine is closed.
+func (e *Engine) listen(parent context.Context, channel string, cb func(string) error) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	go (func() {
+		select {
+		case <-e.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	})()
+
+	conn, err := e.conn.Acquire(ctx)
 	if err != nil {
 		return err
 	}
+	defer conn.Release()
 
-	channelEncoded := fmt.Sprintf("\"%x\"", md5.Sum(channel))
-	if _, err := conn.Exec(context.Background(), "LISTEN "+channelEncoded); err != nil {
+	quoted := fmt.Sprintf("\"%s\"", channel)
+	if _, err := conn.Exec(ctx, "LISTEN "+quoted); err != nil {
 		return fmt.Errorf("database::listen::err %s", err.Error())
 	}
 
+	// the connection goes back to the pool, so it must stop listening first
+	defer conn.Exec(context.Background(), "UNLISTEN "+quoted)
+
 	for {
-		notification, err := conn.Conn().WaitForNotification(context.Background())
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil && parent.Err() != nil {
+			return parent.Err()
+		}
+
 		if err != nil {
 			return fmt.Errorf("database::notification::err %s", err.Error())
 		}
 
-		if err := cb([]byte(notification.Payload)); err != nil {
+		if err := cb(notification.Payload); err != nil {
 			return fmt.Errorf("unable to process notification due to: %s", err.Error())
 		}
 	}
@@ -0,0 +1,82 @@
+package postgresql
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// Stats reports the connection pool and the storage stats
+func (e *Engine) Stats() (map[string]interface{}, error) {
+	var tableSize int64
+
+	if err := e.conn.QueryRow(
+		context.Background(),
+		"SELECT pg_total_relation_size('redix_data_v5')",
+	).Scan(&tableSize); err != nil {
+		return nil, err
+	}
+
+	pool := e.conn.Stat()
+
+	result := map[string]interface{}{
+		"pool_max_conns":             pool.MaxConns(),
+		"pool_total_conns":           pool.TotalConns(),
+		"pool_idle_conns":            pool.IdleConns(),
+		"pool_acquired_conns":        pool.AcquiredConns(),
+		"pool_acquire_count":         pool.AcquireCount(),
+		"pool_acquire_duration_usec": pool.AcquireDuration().Microseconds(),
+		"pool_empty_acquire_count":   pool.EmptyAcquireCount(),
+		"table_size_bytes":           tableSize,
+		"expired_keys":               atomic.LoadInt64(&e.expiredKeys),
+	}
+
+	if e.readConn != nil {
+		replicaPool := e.readConn.Stat()
+
+		result["replica_pool_total_conns"] = replicaPool.TotalConns()
+		result["replica_pool_idle_conns"] = replicaPool.IdleConns()
+		result["replica_pool_acquired_conns"] = replicaPool.AcquiredConns()
+	}
+
+	return result, nil
+}
+
+// Keyspace reports the number of keys per db index
+func (e *Engine) Keyspace() (map[int]*contract.KeyspaceStats, error) {
+	rows, err := e.reader().Query(
+		context.Background(),
+		`
+			SELECT split_part(_key, '/', 2) AS _db, count(*), count(*) FILTER (WHERE _expires_at != 0)
+			FROM redix_data_v5
+			WHERE _key ~ '^/[0-9]+/'
+			GROUP BY _db
+		`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[int]*contract.KeyspaceStats{}
+
+	for rows.Next() {
+		var db string
+		var stats contract.KeyspaceStats
+
+		if err := rows.Scan(&db, &stats.Keys, &stats.Expires); err != nil {
+			return nil, err
+		}
+
+		index, err := strconv.Atoi(db)
+		if err != nil {
+			continue
+		}
+
+		result[index] = &stats
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,89 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// serializationFailure the sqlstate of the transactions conflicting with concurrent ones
+const serializationFailure = "40001"
+
+// transaction represents a contract.Transaction backed by a database transaction
+type transaction struct {
+	*Engine
+	tx pgx.Tx
+}
+
+// Begin starts a new serializable database transaction, so it is atomic against the writes of all the nodes
+// sharing the database, contract.ErrTransactionConflict is returned once it conflicts with a concurrent one.
+func (e *Engine) Begin() (contract.Transaction, error) {
+	tx, err := e.conn.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{Engine: e, tx: tx}, nil
+}
+
+// Write writes into the transaction
+func (t *transaction) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	ret, err := t.write(t.tx, input)
+
+	return ret, conflictOf(err)
+}
+
+// Read reads from the transaction, the deletions are applied within the transaction too
+func (t *transaction) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	if input == nil {
+		return nil, fmt.Errorf("empty input specified")
+	}
+
+	readOutput, err := t.read(t.tx, input.Key)
+	if err != nil || !readOutput.Exists {
+		return readOutput, conflictOf(err)
+	}
+
+	// the expired keys are left to the expired keys cleaner
+	if readOutput.TTL < 0 {
+		return &contract.ReadOutput{}, nil
+	}
+
+	if input.Delete {
+		if _, err := t.tx.Exec(context.Background(), "DELETE FROM redix_data_v5 WHERE _key = $1", input.Key); err != nil {
+			return nil, conflictOf(err)
+		}
+	}
+
+	return readOutput, nil
+}
+
+// Iterate iterates on the whole database as seen by the transaction
+func (t *transaction) Iterate(opts *contract.IteratorOpts) error {
+	return conflictOf(t.iterate(t.tx, opts))
+}
+
+// Commit commits the database transaction
+func (t *transaction) Commit() error {
+	return conflictOf(t.tx.Commit(context.Background()))
+}
+
+// Rollback rolls the database transaction back
+func (t *transaction) Rollback() error {
+	return t.tx.Rollback(context.Background())
+}
+
+// conflictOf returns contract.ErrTransactionConflict if the specified error is a serialization failure
+func conflictOf(err error) error {
+	var pgErr *pgconn.PgError
+
+	if errors.As(err, &pgErr) && pgErr.Code == serializationFailure {
+		return contract.ErrTransactionConflict
+	}
+
+	return err
+}
@@ -0,0 +1,269 @@
+// Package router implements a contract.Engine dispatching each key to one of several engines
+package router
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// Route represents a routing rule, a key matches it if it belongs to
+// the specified db (if any) and starts with the specified prefix (if any).
+type Route struct {
+	// DB the db index, a negative value matches all of the db indexes
+	DB int
+
+	// Prefix the key prefix relative to the db
+	Prefix []byte
+
+	// Engine the name of the engine the matched keys are stored in
+	Engine string
+}
+
+// Router represents the contract.Engine implementation routing the keys to the named engines,
+// the pub/sub is always handled by the default engine.
+type Router struct {
+	engines  map[string]contract.Engine
+	names    []string
+	routes   []*Route
+	fallback string
+}
+
+// New creates a new router over the specified engines, the keys not matching any of the routes
+// (which are evaluated in order) are stored in the fallback engine.
+func New(engines map[string]contract.Engine, routes []*Route, fallback string) (*Router, error) {
+	if _, exists := engines[fallback]; !exists {
+		return nil, fmt.Errorf("unknown default engine (%s) specified", fallback)
+	}
+
+	r := &Router{
+		engines:  engines,
+		routes:   routes,
+		fallback: fallback,
+	}
+
+	for name := range engines {
+		if name != fallback {
+			r.names = append(r.names, name)
+		}
+	}
+
+	sort.Strings(r.names)
+
+	r.names = append([]string{fallback}, r.names...)
+
+	for _, route := range routes {
+		if _, exists := engines[route.Engine]; !exists {
+			return nil, fmt.Errorf("unknown engine (%s) specified in the routes", route.Engine)
+		}
+	}
+
+	return r, nil
+}
+
+// Route returns the name of the engine the specified absolute key is stored in
+func (r *Router) Route(key []byte) string {
+	db, relativeKey, ok := contract.SplitKey(key)
+	if !ok {
+		return r.fallback
+	}
+
+	for _, route := range r.routes {
+		if route.DB >= 0 && route.DB != db {
+			continue
+		}
+
+		if bytes.HasPrefix(relativeKey, route.Prefix) {
+			return route.Engine
+		}
+	}
+
+	return r.fallback
+}
+
+// Engine returns the engine the specified absolute key is stored in
+func (r *Router) Engine(key []byte) contract.Engine {
+	return r.engines[r.Route(key)]
+}
+
+// Engines returns the names of the engines, the default one comes first
+func (r *Router) Engines() []string {
+	return r.names
+}
+
+// Open isn't supported, the routed engines must be opened before creating the router
+func (r *Router) Open(string) error {
+	return fmt.Errorf("the router engines must be opened individually")
+}
+
+// Close closes all of the engines
+func (r *Router) Close() error {
+	var firstErr error
+
+	for _, name := range r.names {
+		if err := r.engines[name].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Write writes into the engine of the specified key, the flushes are applied to all of the affected engines
+func (r *Router) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	if input == nil {
+		return nil, fmt.Errorf("empty input specified")
+	}
+
+	if input.Key != nil && input.Value != nil {
+		return r.Engine(input.Key).Write(input)
+	}
+
+	for _, engine := range r.candidates(input.Key) {
+		if _, err := engine.Write(input); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Read reads from the engine of the specified key
+func (r *Router) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	if input == nil {
+		return nil, fmt.Errorf("empty input specified")
+	}
+
+	return r.Engine(input.Key).Read(input)
+}
+
+// Iterate iterates over all of the engines that may store keys having the specified prefix
+func (r *Router) Iterate(opts *contract.IteratorOpts) error {
+	if opts == nil {
+		return fmt.Errorf("empty options specified")
+	}
+
+	for _, engine := range r.candidates(opts.Prefix) {
+		if err := engine.Iterate(opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Publish publishes using the default engine
+func (r *Router) Publish(channel []byte, payload []byte) error {
+	return r.engines[r.fallback].Publish(channel, payload)
+}
+
+// Subscribe subscribes using the default engine
+func (r *Router) Subscribe(channel []byte, cb func([]byte) error) error {
+	return r.engines[r.fallback].Subscribe(channel, cb)
+}
+
+// SubscribeContext subscribes using the default engine till the context is done
+func (r *Router) SubscribeContext(ctx context.Context, channel []byte, cb func([]byte) error) error {
+	return contract.SubscribeContext(ctx, r.engines[r.fallback], channel, cb)
+}
+
+// PSubscribe subscribes to the channels matching the pattern using the default engine
+func (r *Router) PSubscribe(ctx context.Context, pattern []byte, cb func([]byte, []byte) error) error {
+	return contract.PSubscribe(ctx, r.engines[r.fallback], pattern, cb)
+}
+
+// WriteBatch writes the specified inputs at once if all of them are stored in the same engine
+// and that engine supports batch writes, otherwise contract.ErrBatchUnsupported is returned.
+func (r *Router) WriteBatch(inputs []*contract.WriteInput) error {
+	name := ""
+
+	for _, input := range inputs {
+		if input == nil || input.Key == nil || input.Value == nil {
+			return contract.ErrBatchUnsupported
+		}
+
+		if n := r.Route(input.Key); name == "" {
+			name = n
+		} else if n != name {
+			return contract.ErrBatchUnsupported
+		}
+	}
+
+	batchWriter, ok := r.engines[name].(contract.BatchWriter)
+	if !ok {
+		return contract.ErrBatchUnsupported
+	}
+
+	return batchWriter.WriteBatch(inputs)
+}
+
+// Stats reports the stats of each engine prefixed by its name
+func (r *Router) Stats() (map[string]interface{}, error) {
+	return contract.CombinedStats(r.names, r.engines)
+}
+
+// Keyspace reports the keyspace of all of the engines combined
+func (r *Router) Keyspace() (map[int]*contract.KeyspaceStats, error) {
+	return contract.CombinedKeyspace(r.names, r.engines)
+}
+
+// Rebalance rebalances all of the engines that support that
+func (r *Router) Rebalance() (int64, error) {
+	moved := int64(0)
+	supported := false
+
+	for _, name := range r.names {
+		rebalancer, ok := r.engines[name].(contract.Rebalancer)
+		if !ok {
+			continue
+		}
+
+		supported = true
+
+		n, err := rebalancer.Rebalance()
+		moved += n
+
+		if err != nil {
+			return moved, err
+		}
+	}
+
+	if !supported {
+		return 0, fmt.Errorf("none of the engines supports rebalancing")
+	}
+
+	return moved, nil
+}
+
+// candidates returns the engines that may store keys having the specified absolute prefix
+func (r *Router) candidates(prefix []byte) []contract.Engine {
+	db, relativePrefix, ok := contract.SplitKey(prefix)
+
+	var result []contract.Engine
+
+	for _, name := range r.names {
+		if !ok || name == r.fallback || r.mayStore(name, db, relativePrefix) {
+			result = append(result, r.engines[name])
+		}
+	}
+
+	return result
+}
+
+// mayStore whether any of the routes of the specified engine matches keys having the specified prefix or not
+func (r *Router) mayStore(name string, db int, prefix []byte) bool {
+	for _, route := range r.routes {
+		if route.Engine != name || (route.DB >= 0 && route.DB != db) {
+			continue
+		}
+
+		if bytes.HasPrefix(prefix, route.Prefix) || bytes.HasPrefix(route.Prefix, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,417 @@
+// Package sharded implements a contract.Engine spreading the keys over several engines using consistent hashing
+package sharded
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// Global consts
+const (
+	Name = "sharded"
+
+	// replicas the number of the virtual nodes each shard owns on the ring
+	replicas = 160
+)
+
+// vnode represents a virtual node on the ring
+type vnode struct {
+	hash  uint64
+	shard string
+}
+
+// Engine represents the contract.Engine implementation,
+// the pub/sub is always handled by the first shard.
+type Engine struct {
+	shards map[string]contract.Engine
+	names  []string
+	ring   []vnode
+
+	// rebalancing serializes the rebalances
+	rebalancing sync.Mutex
+
+	// balanced whether all the keys are known to be stored in their shards, till a rebalance completes
+	// the keys missing from their shards are looked up in the other ones and moved on access.
+	balanced int32
+}
+
+// New creates a new sharded engine over the specified engines, the shard a key belongs to
+// depends on the shards names only, so their order doesn't matter and adding a shard
+// only moves the keys it takes over from the other shards.
+func New(shards map[string]contract.Engine, names []string) (*Engine, error) {
+	if len(names) < 1 {
+		return nil, fmt.Errorf("at least one shard must be specified")
+	}
+
+	e := &Engine{
+		shards: shards,
+		names:  names,
+	}
+
+	for _, name := range names {
+		if _, exists := shards[name]; !exists {
+			return nil, fmt.Errorf("unknown shard (%s) specified", name)
+		}
+
+		for i := 0; i < replicas; i++ {
+			e.ring = append(e.ring, vnode{
+				hash:  hash([]byte(name + "#" + strconv.Itoa(i))),
+				shard: name,
+			})
+		}
+	}
+
+	sort.Slice(e.ring, func(i, j int) bool {
+		return e.ring[i].hash < e.ring[j].hash
+	})
+
+	if len(names) == 1 {
+		e.balanced = 1
+	}
+
+	return e, nil
+}
+
+// Shard returns the name of the shard the specified key belongs to
+func (e *Engine) Shard(key []byte) string {
+	h := hash(key)
+
+	i := sort.Search(len(e.ring), func(i int) bool {
+		return e.ring[i].hash >= h
+	})
+
+	if i == len(e.ring) {
+		i = 0
+	}
+
+	return e.ring[i].shard
+}
+
+// Open isn't supported, the shards must be opened before creating the sharded engine
+func (e *Engine) Open(string) error {
+	return fmt.Errorf("the %s engine shards must be opened individually", Name)
+}
+
+// Close closes all of the shards
+func (e *Engine) Close() error {
+	var firstErr error
+
+	for _, name := range e.names {
+		if err := e.shards[name].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Write writes into the shard of the specified key, the flushes are applied to all of the shards
+func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	if input == nil {
+		return nil, fmt.Errorf("empty input specified")
+	}
+
+	if input.Key != nil && input.Value != nil {
+		// the appends and the increments must apply to the current value wherever it is stored
+		if err := e.settle(input.Key); err != nil {
+			return nil, err
+		}
+
+		return e.shards[e.Shard(input.Key)].Write(input)
+	}
+
+	return nil, e.each(func(_ int, shard contract.Engine) error {
+		_, err := shard.Write(input)
+		return err
+	})
+}
+
+// Read reads from the shard of the specified key, the key is moved there first if it is stored in another shard
+func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	if input == nil {
+		return nil, fmt.Errorf("empty input specified")
+	}
+
+	if err := e.settle(input.Key); err != nil {
+		return nil, err
+	}
+
+	return e.shards[e.Shard(input.Key)].Read(input)
+}
+
+// settle moves the specified key to its shard if it is missing from there but stored in another shard,
+// it does nothing once the shards are balanced.
+func (e *Engine) settle(key []byte) error {
+	if atomic.LoadInt32(&e.balanced) == 1 {
+		return nil
+	}
+
+	owner := e.Shard(key)
+
+	ro, err := e.shards[owner].Read(&contract.ReadInput{Key: key})
+	if err != nil {
+		return err
+	}
+
+	if ro.Exists {
+		return nil
+	}
+
+	for _, name := range e.names {
+		if name == owner {
+			continue
+		}
+
+		ro, err := e.shards[name].Read(&contract.ReadInput{Key: key})
+		if err != nil {
+			return err
+		}
+
+		if !ro.Exists {
+			continue
+		}
+
+		if _, err := e.move(e.shards[name], key); err != nil {
+			return fmt.Errorf("unable to move the key (%s) from the shard (%s) due to: %s", key, name, err.Error())
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// Iterate iterates over all of the shards concurrently then calls the callback in key order,
+// the results are buffered in memory to be merged.
+func (e *Engine) Iterate(opts *contract.IteratorOpts) error {
+	if opts == nil {
+		return fmt.Errorf("empty options specified")
+	}
+
+	if opts.Callback == nil {
+		return fmt.Errorf("you must specify the callback")
+	}
+
+	results := make([][]*contract.ReadOutput, len(e.names))
+
+	err := e.each(func(i int, shard contract.Engine) error {
+		var result []*contract.ReadOutput
+
+		err := shard.Iterate(&contract.IteratorOpts{
+			Prefix: opts.Prefix,
+			Callback: func(ro *contract.ReadOutput) error {
+				result = append(result, ro)
+				return nil
+			},
+		})
+
+		results[i] = result
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var merged []*contract.ReadOutput
+	for _, result := range results {
+		merged = append(merged, result...)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return bytes.Compare(merged[i].Key, merged[j].Key) < 0
+	})
+
+	for _, ro := range merged {
+		if err := opts.Callback(ro); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Publish publishes using the first shard
+func (e *Engine) Publish(channel []byte, payload []byte) error {
+	return e.shards[e.names[0]].Publish(channel, payload)
+}
+
+// Subscribe subscribes using the first shard
+func (e *Engine) Subscribe(channel []byte, cb func([]byte) error) error {
+	return e.shards[e.names[0]].Subscribe(channel, cb)
+}
+
+// SubscribeContext subscribes using the first shard till the context is done
+func (e *Engine) SubscribeContext(ctx context.Context, channel []byte, cb func([]byte) error) error {
+	return contract.SubscribeContext(ctx, e.shards[e.names[0]], channel, cb)
+}
+
+// PSubscribe subscribes to the channels matching the pattern using the first shard
+func (e *Engine) PSubscribe(ctx context.Context, pattern []byte, cb func([]byte, []byte) error) error {
+	return contract.PSubscribe(ctx, e.shards[e.names[0]], pattern, cb)
+}
+
+// WriteBatch writes the specified inputs at once if all of them belong to the same shard
+// and that shard supports batch writes, otherwise contract.ErrBatchUnsupported is returned.
+func (e *Engine) WriteBatch(inputs []*contract.WriteInput) error {
+	name := ""
+
+	for _, input := range inputs {
+		if input == nil || input.Key == nil || input.Value == nil {
+			return contract.ErrBatchUnsupported
+		}
+
+		if n := e.Shard(input.Key); name == "" {
+			name = n
+		} else if n != name {
+			return contract.ErrBatchUnsupported
+		}
+	}
+
+	batchWriter, ok := e.shards[name].(contract.BatchWriter)
+	if !ok {
+		return contract.ErrBatchUnsupported
+	}
+
+	// the appends and the increments must apply to the current values wherever they are stored
+	for _, input := range inputs {
+		if err := e.settle(input.Key); err != nil {
+			return err
+		}
+	}
+
+	return batchWriter.WriteBatch(inputs)
+}
+
+// Rebalance moves the keys stored in the wrong shards (i.e after adding a shard) to the shards they belong to,
+// the keys aren't looked up in the other shards anymore once it completes.
+func (e *Engine) Rebalance() (int64, error) {
+	e.rebalancing.Lock()
+	defer e.rebalancing.Unlock()
+
+	moved := int64(0)
+
+	for _, name := range e.names {
+		shard := e.shards[name]
+
+		var misplaced [][]byte
+
+		if err := shard.Iterate(&contract.IteratorOpts{
+			Prefix: []byte(""),
+			Callback: func(ro *contract.ReadOutput) error {
+				if e.Shard(ro.Key) != name {
+					misplaced = append(misplaced, append([]byte(nil), ro.Key...))
+				}
+				return nil
+			},
+		}); err != nil {
+			return moved, err
+		}
+
+		for _, key := range misplaced {
+			ok, err := e.move(shard, key)
+			if err != nil {
+				return moved, fmt.Errorf("unable to move the key (%s) from the shard (%s) due to: %s", key, name, err.Error())
+			}
+
+			if ok {
+				moved++
+			}
+		}
+	}
+
+	atomic.StoreInt32(&e.balanced, 1)
+
+	return moved, nil
+}
+
+// Stats reports the stats of each shard prefixed by its name
+func (e *Engine) Stats() (map[string]interface{}, error) {
+	return contract.CombinedStats(e.names, e.shards)
+}
+
+// Keyspace reports the keyspace of all of the shards combined
+func (e *Engine) Keyspace() (map[int]*contract.KeyspaceStats, error) {
+	return contract.CombinedKeyspace(e.names, e.shards)
+}
+
+// move copies the specified key from the specified shard to the shard it belongs to, then deletes it from the former,
+// the copy doesn't overwrite the key if it has been written to its shard meanwhile, as the moved value is older.
+func (e *Engine) move(from contract.Engine, key []byte) (bool, error) {
+	ro, err := from.Read(&contract.ReadInput{Key: key})
+	if err != nil {
+		return false, err
+	}
+
+	// it has been deleted or expired meanwhile
+	if ro == nil || !ro.Exists {
+		return false, nil
+	}
+
+	input := &contract.WriteInput{
+		Key:             key,
+		Value:           ro.Value,
+		OnlyIfNotExists: true,
+	}
+
+	if ro.TTL > 0 {
+		input.TTL = ro.TTL
+	}
+
+	if _, err := e.shards[e.Shard(key)].Write(input); err != nil {
+		return false, err
+	}
+
+	// the deleting read removes the exact key only, unlike the deleting writes which remove the prefixed keys too
+	if _, err := from.Read(&contract.ReadInput{Key: key, Delete: true}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// each calls fn for each shard (along with its index) concurrently, it returns the first error occurred
+func (e *Engine) each(fn func(int, contract.Engine) error) error {
+	errs := make(chan error, len(e.names))
+
+	for i, name := range e.names {
+		go (func(i int, shard contract.Engine) {
+			errs <- fn(i, shard)
+		})(i, e.shards[name])
+	}
+
+	var firstErr error
+
+	for range e.names {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// hash hashes the specified data onto the ring
+func hash(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+
+	// fnv alone clusters the keys differing in their last bytes only,
+	// so its result is mixed (as murmur3 finalizes its hashes) to spread them over the ring.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return x
+}
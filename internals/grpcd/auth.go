@@ -0,0 +1,78 @@
+package grpcd
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"github.com/alash3al/redix/internals/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizeUnary rejects the unary calls of the clients that aren't authenticated
+func (s *Server) authorizeUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// authorizeStream rejects the streaming calls of the clients that aren't authenticated
+func (s *Server) authorizeStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := authorize(ss.Context()); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// authorize authenticates the call using the credentials of its metadata once a user is configured (the same users
+// of the http interface), either a basic "authorization" header or the "username" and "password" keys.
+func authorize(ctx context.Context) error {
+	cfg := config.Current()
+	if cfg != nil && len(cfg.Users) < 1 {
+		return nil
+	}
+
+	username, password, ok := credentialsOf(ctx)
+	if !ok {
+		return status.Errorf(codes.Unauthenticated, "NOAUTH Authentication required.")
+	}
+
+	if cfg == nil || cfg.Authenticate(username, password) == nil {
+		return status.Errorf(codes.Unauthenticated, "WRONGPASS invalid username-password pair or user is disabled.")
+	}
+
+	return nil
+}
+
+// credentialsOf returns the username and the password found in the metadata of the specified call
+func credentialsOf(ctx context.Context) (string, string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get("authorization"); len(values) > 0 {
+		const prefix = "basic "
+
+		if len(values[0]) < len(prefix) || !strings.EqualFold(values[0][:len(prefix)], prefix) {
+			return "", "", false
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(values[0][len(prefix):])
+		if err != nil {
+			return "", "", false
+		}
+
+		return strings.Cut(string(decoded), ":")
+	}
+
+	usernames, passwords := md.Get("username"), md.Get("password")
+	if len(usernames) < 1 || len(passwords) < 1 {
+		return "", "", false
+	}
+
+	return usernames[0], passwords[0], true
+}
@@ -0,0 +1,423 @@
+package grpcd
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alash3al/redix/internals/config"
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/redis/commands"
+	"github.com/alash3al/redix/internals/replication"
+	"github.com/alash3al/redix/internals/stats"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// the range page sizes
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// Get implements KV.Get
+func (s *Server) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	return doGet(s.engine, req.Db, req)
+}
+
+// Put implements KV.Put, the write is applied through the write-behind queue if the async writes are enabled
+func (s *Server) Put(ctx context.Context, req *PutRequest) (*PutResponse, error) {
+	writeOpts, err := writeInput(req.Db, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writable(); err != nil {
+		return nil, err
+	}
+
+	if cfg := config.Current(); cfg != nil && cfg.Server.Redis.AsyncWrites {
+		err = s.queue.Enqueue(writeOpts)
+	} else {
+		_, err = s.engine.Write(writeOpts)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PutResponse{}, nil
+}
+
+// Delete implements KV.Delete
+func (s *Server) Delete(ctx context.Context, req *DeleteRequest) (*DeleteResponse, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+
+	return doDelete(s.engine, req.Db, req)
+}
+
+// Range implements KV.Range, it replies with a page of the keys having the prefix sorted by their names
+func (s *Server) Range(ctx context.Context, req *RangeRequest) (*RangeResponse, error) {
+	namespace, err := namespaceOf(req.Db)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	if limit < 0 || limit > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid limit, it must be between 1 and %d", maxPageSize)
+	}
+
+	cursor := absoluteKey(namespace, req.Cursor)
+	hasCursor := len(req.Cursor) > 0
+
+	// the engines don't guarantee an order, so only the smallest keys after the cursor are kept while iterating,
+	// one more key than the limit tells whether there is a next page.
+	page := &pageHeap{}
+
+	err = s.engine.Iterate(&contract.IteratorOpts{
+		Prefix: absoluteKey(namespace, req.Prefix),
+		Callback: func(ro *contract.ReadOutput) error {
+			if hasCursor && bytes.Compare(ro.Key, cursor) <= 0 {
+				return nil
+			}
+
+			if int64(page.Len()) > limit && bytes.Compare(ro.Key, (*page)[0].Key) >= 0 {
+				return nil
+			}
+
+			heap.Push(page, ro)
+
+			if int64(page.Len()) > limit+1 {
+				heap.Pop(page)
+			}
+
+			return nil
+		},
+	})
+
+	if err != nil && err != contract.ErrStopIterator {
+		return nil, err
+	}
+
+	result := []*contract.ReadOutput(*page)
+
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Key, result[j].Key) < 0
+	})
+
+	res := &RangeResponse{}
+
+	for _, ro := range result {
+		if int64(len(res.Kvs)) == limit {
+			res.Cursor = res.Kvs[len(res.Kvs)-1].Key
+			break
+		}
+
+		kv := &KeyValue{
+			Key: bytes.TrimPrefix(ro.Key, namespace),
+			Ttl: ro.TTL.Milliseconds(),
+		}
+
+		if !req.KeysOnly {
+			kv.Value = ro.Value
+		}
+
+		res.Kvs = append(res.Kvs, kv)
+	}
+
+	return res, nil
+}
+
+// txn implements KV.Txn, the compares and the operations are applied within a (serializable) transaction
+// if the engine supports that, otherwise they run exclusively like the redis scripts, so the redis commands
+// of the same node can't interleave with them, a transaction conflicting with concurrent writes is aborted.
+func (s *Server) Txn(ctx context.Context, req *TxnRequest) (*TxnResponse, error) {
+	namespace, err := namespaceOf(req.Db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range append(append([]*Op(nil), req.Success...), req.Failure...) {
+		if op.Request == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "empty operation specified")
+		}
+
+		if op.GetGet() == nil {
+			if err := writable(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	engine, tx, err := begin(s.engine)
+	if err != nil {
+		return nil, err
+	}
+
+	defer commands.Lock(tx == nil)()
+
+	res, err := applyTxn(engine, namespace, req)
+	if err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+
+		return nil, conflictOf(err)
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, status.Errorf(codes.Aborted, "unable to commit the transaction due to: %s", err.Error())
+		}
+	}
+
+	return res, nil
+}
+
+// conflictOf returns an aborted status error if the specified error is a transaction conflict, otherwise it returns it as is
+func conflictOf(err error) error {
+	if err == contract.ErrTransactionConflict {
+		return status.Errorf(codes.Aborted, "the transaction conflicted with concurrent writes, retry it")
+	}
+
+	return err
+}
+
+// applyTxn checks the compares of the specified transaction then applies its operations using the specified engine
+func applyTxn(engine contract.Engine, namespace []byte, req *TxnRequest) (*TxnResponse, error) {
+	res := &TxnResponse{Succeeded: true}
+
+	for _, cmp := range req.Compares {
+		if len(cmp.Key) < 1 {
+			return nil, status.Errorf(codes.InvalidArgument, "empty compare key specified")
+		}
+
+		ro, err := engine.Read(&contract.ReadInput{
+			Key: absoluteKey(namespace, cmp.Key),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var holds bool
+
+		switch cmp.Condition {
+		case Compare_EQUAL:
+			holds = ro.Exists && bytes.Equal(ro.Value, cmp.Value)
+		case Compare_NOT_EQUAL:
+			holds = !ro.Exists || !bytes.Equal(ro.Value, cmp.Value)
+		case Compare_EXISTS:
+			holds = ro.Exists
+		case Compare_NOT_EXISTS:
+			holds = !ro.Exists
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown compare condition (%d)", cmp.Condition)
+		}
+
+		if !holds {
+			res.Succeeded = false
+			break
+		}
+	}
+
+	ops := req.Success
+	if !res.Succeeded {
+		ops = req.Failure
+	}
+
+	for _, op := range ops {
+		opRes := &OpResponse{}
+
+		var err error
+
+		switch request := op.Request.(type) {
+		case *Op_Get:
+			var getRes *GetResponse
+
+			getRes, err = doGet(engine, req.Db, request.Get)
+			opRes.Response = &OpResponse_Get{Get: getRes}
+		case *Op_Put:
+			var writeOpts *contract.WriteInput
+
+			if writeOpts, err = writeInput(req.Db, request.Put); err == nil {
+				_, err = engine.Write(writeOpts)
+				opRes.Response = &OpResponse_Put{Put: &PutResponse{}}
+			}
+		case *Op_Delete:
+			var deleteRes *DeleteResponse
+
+			deleteRes, err = doDelete(engine, req.Db, request.Delete)
+			opRes.Response = &OpResponse_Delete{Delete: deleteRes}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		res.Responses = append(res.Responses, opRes)
+	}
+
+	return res, nil
+}
+
+// begin starts a transaction the writes are applied within if the engine supports that,
+// otherwise the engine itself is used.
+func begin(engine contract.Engine) (contract.Engine, contract.Transaction, error) {
+	transactor, ok := engine.(contract.Transactor)
+	if !ok {
+		return engine, nil, nil
+	}
+
+	tx, err := transactor.Begin()
+	if err == contract.ErrTransactionUnsupported {
+		return engine, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tx, tx, nil
+}
+
+// doGet fetches the key of the specified request from the specified db
+func doGet(engine contract.Engine, db int64, req *GetRequest) (*GetResponse, error) {
+	namespace, err := namespaceOf(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Key) < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "empty key specified")
+	}
+
+	ro, err := engine.Read(&contract.ReadInput{
+		Key: absoluteKey(namespace, req.Key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !ro.Exists {
+		stats.KeyspaceMiss()
+		return &GetResponse{}, nil
+	}
+
+	stats.KeyspaceHit()
+
+	return &GetResponse{
+		Found: true,
+		Kv: &KeyValue{
+			Key:   req.Key,
+			Value: ro.Value,
+			Ttl:   ro.TTL.Milliseconds(),
+		},
+	}, nil
+}
+
+// doDelete deletes the key of the specified request from the specified db
+func doDelete(engine contract.Engine, db int64, req *DeleteRequest) (*DeleteResponse, error) {
+	namespace, err := namespaceOf(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Key) < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "empty key specified")
+	}
+
+	ro, err := engine.Read(&contract.ReadInput{
+		Key:    absoluteKey(namespace, req.Key),
+		Delete: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteResponse{Deleted: ro != nil && ro.Exists}, nil
+}
+
+// writeInput validates the specified put request then returns its write input
+func writeInput(db int64, req *PutRequest) (*contract.WriteInput, error) {
+	namespace, err := namespaceOf(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Key) < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "empty key specified")
+	}
+
+	if req.Ttl < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid ttl, it must not be negative")
+	}
+
+	value := req.Value
+	if value == nil {
+		// a nil value deletes the keys having the key as their prefix
+		value = []byte{}
+	}
+
+	return &contract.WriteInput{
+		Key:   absoluteKey(namespace, req.Key),
+		Value: value,
+		TTL:   time.Millisecond * time.Duration(req.Ttl),
+	}, nil
+}
+
+// writable returns an error if the writes aren't allowed
+func writable() error {
+	if replication.IsReplica() {
+		return status.Errorf(codes.FailedPrecondition, "READONLY You can't write against a read only replica.")
+	}
+
+	if cfg := config.Current(); cfg != nil && cfg.Server.Redis.ReadOnly {
+		return status.Errorf(codes.FailedPrecondition, "READONLY You can't write against a read only server.")
+	}
+
+	return nil
+}
+
+// namespaceOf returns the prefix of the keys of the specified db
+func namespaceOf(db int64) ([]byte, error) {
+	if db < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db index")
+	}
+
+	return []byte("/" + strconv.FormatInt(db, 10) + "/"), nil
+}
+
+// absoluteKey returns the full path of the specified key, it is the same as the one used by the redis commands
+func absoluteKey(namespace []byte, key []byte) []byte {
+	return append(append([]byte(nil), namespace...), strings.TrimLeft(string(key), "/")...)
+}
+
+// pageHeap is a max heap of the keys of a page, so its greatest key is evicted once it is full
+type pageHeap []*contract.ReadOutput
+
+func (h pageHeap) Len() int           { return len(h) }
+func (h pageHeap) Less(i, j int) bool { return bytes.Compare(h[i].Key, h[j].Key) > 0 }
+func (h pageHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *pageHeap) Push(x interface{}) {
+	*h = append(*h, x.(*contract.ReadOutput))
+}
+
+func (h *pageHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return item
+}
@@ -0,0 +1,144 @@
+// Package grpcd implements the grpc interface, it serves the KV service of kv.proto
+// using the stubs generated from it.
+package grpcd
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative kv.proto
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alash3al/redix/internals/config"
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/metrics"
+	"github.com/alash3al/redix/internals/redis"
+	"github.com/alash3al/redix/internals/stats"
+	"github.com/alash3al/redix/internals/writebehind"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// the largest accepted request message
+const maxMessageSize = 4 << 20
+
+// Server represents a grpc interface server
+type Server struct {
+	UnimplementedKVServer
+
+	engine contract.Engine
+	queue  *writebehind.Queue
+	addr   string
+	srv    *grpc.Server
+
+	closeOnce sync.Once
+
+	// closing is closed once the server is shutting down, so the watchers end
+	closing chan struct{}
+}
+
+// NewServer creates a new grpc server that serves the specified engine, the listener uses the specified
+// tls configs (the ones of the redis listener) if not nil, and the calls are authenticated using the configured users.
+func NewServer(cfg *config.GRPCConfig, tlsCfg *config.TLSConfig, engine contract.Engine, queue *writebehind.Queue) (*Server, error) {
+	s := &Server{
+		engine:  engine,
+		queue:   queue,
+		addr:    cfg.ListenAddr,
+		closing: make(chan struct{}),
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(maxMessageSize),
+		grpc.ChainUnaryInterceptor(s.authorizeUnary, s.measure),
+		grpc.ChainStreamInterceptor(s.authorizeStream, s.translate),
+	}
+
+	if tlsCfg != nil {
+		tlsConfig, err := redis.NewTLSConfig(tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	s.srv = grpc.NewServer(opts...)
+
+	RegisterKVServer(s.srv, s)
+
+	return s, nil
+}
+
+// ListenAndServe starts the grpc server, it returns nil once the server is shutdown
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	if err := s.srv.Serve(ln); err != nil && err != grpc.ErrServerStopped {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown gracefully shuts down the server, the watchers are ended and the in-flight calls are awaited,
+// the calls still running once the context is done are canceled.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.closing)
+	})
+
+	done := make(chan struct{})
+
+	go (func() {
+		s.srv.GracefulStop()
+		close(done)
+	})()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// measure records the stats of the unary calls, the errors that aren't grpc status errors are internal ones
+func (s *Server) measure(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	name := "grpc." + strings.ToLower(info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:])
+
+	start := time.Now()
+	res, err := handler(ctx, req)
+	elapsed := time.Since(start)
+
+	stats.CommandProcessed(name, elapsed, err != nil)
+	metrics.ObserveCommand(name, elapsed)
+
+	return res, statusOf(err)
+}
+
+// translate converts the errors of the streaming calls to grpc status errors, such calls aren't measured
+// as they are long living.
+func (s *Server) translate(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return statusOf(handler(srv, ss))
+}
+
+// statusOf returns the specified error as a grpc status error, the errors that aren't status errors are internal ones
+func statusOf(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
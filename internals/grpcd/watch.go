@@ -0,0 +1,155 @@
+package grpcd
+
+import (
+	"bytes"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/replication"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// the number of the writes buffered for a watcher before it is considered too slow
+const watchBuffer = 1024
+
+// Watch implements KV.Watch, it streams the writes recorded in the write log (the replication backlog)
+// that affect the keys having the prefix, the expired keys aren't streamed as they aren't recorded,
+// neither are the writes of the other nodes sharing the same storage (i.e a postgresql database).
+func (s *Server) Watch(req *WatchRequest, st KV_WatchServer) error {
+	namespace, err := namespaceOf(req.Db)
+	if err != nil {
+		return err
+	}
+
+	if req.StartRevision < 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid start revision")
+	}
+
+	w, err := replication.Watch(req.Replid, req.StartRevision, watchBuffer)
+	if err == replication.ErrReplIDMismatch {
+		return status.Errorf(codes.OutOfRange, "the revision %d belongs to another write log (%q), the current one is %q", req.StartRevision, req.Replid, replication.ReplID())
+	}
+
+	if err == replication.ErrOffsetUnavailable {
+		return status.Errorf(codes.OutOfRange, "the revision %d isn't in the write log anymore", req.StartRevision)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go (func() {
+		select {
+		case <-done:
+		case <-st.Context().Done():
+		case <-s.closing:
+		}
+
+		w.Close()
+	})()
+
+	// the headers tell the client the watcher has been registered
+	if err := st.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	prefix := absoluteKey(namespace, req.Prefix)
+
+	for {
+		entry, err := w.Next()
+
+		switch {
+		case err == replication.ErrWatcherLost:
+			return status.Errorf(codes.Aborted, "the watcher couldn't keep up with the writes, resume it from the last received revision")
+		case err == replication.ErrWatcherClosed:
+			select {
+			case <-s.closing:
+				return status.Errorf(codes.Unavailable, "the server is shutting down")
+			default:
+				return nil
+			}
+		case err != nil:
+			return err
+		}
+
+		event, err := s.eventOf(entry, namespace, prefix)
+		if err != nil {
+			return err
+		}
+
+		if event == nil {
+			continue
+		}
+
+		if err := st.Send(event); err != nil {
+			return err
+		}
+	}
+}
+
+// eventOf returns the event of the specified write if it affects the keys having the specified prefix
+func (s *Server) eventOf(entry *replication.Entry, namespace, prefix []byte) (*WatchEvent, error) {
+	input := entry.Input
+
+	event := &WatchEvent{
+		Revision: entry.Offset,
+		Replid:   entry.ReplID,
+		Kv:       &KeyValue{},
+	}
+
+	switch {
+	case entry.Delete:
+		if !bytes.HasPrefix(input.Key, prefix) {
+			return nil, nil
+		}
+
+		event.Type = WatchEvent_DELETE
+		event.Kv.Key = bytes.TrimPrefix(input.Key, namespace)
+	case input.Key == nil || input.Value == nil:
+		// the writes without a value delete the keys having their key as a prefix, and the ones without a key delete all
+		deleted := input.Key
+
+		if !bytes.HasPrefix(deleted, prefix) && !bytes.HasPrefix(prefix, deleted) {
+			return nil, nil
+		}
+
+		event.Type = WatchEvent_DELETE
+		event.Prefix = true
+
+		if bytes.HasPrefix(deleted, namespace) {
+			event.Kv.Key = bytes.TrimPrefix(deleted, namespace)
+		}
+	default:
+		if !bytes.HasPrefix(input.Key, prefix) {
+			return nil, nil
+		}
+
+		event.Type = WatchEvent_PUT
+		event.Kv.Key = bytes.TrimPrefix(input.Key, namespace)
+		event.Kv.Value = input.Value
+		event.Kv.Ttl = input.TTL.Milliseconds()
+
+		// the value of the key is only known once written unless it has been set as is
+		if input.Increment || input.Append || input.OnlyIfNotExists || input.KeepTTL {
+			ro, err := s.engine.Read(&contract.ReadInput{
+				Key: input.Key,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if !ro.Exists {
+				return nil, nil
+			}
+
+			event.Kv.Value = ro.Value
+			event.Kv.Ttl = ro.TTL.Milliseconds()
+		}
+	}
+
+	return event, nil
+}
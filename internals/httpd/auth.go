@@ -0,0 +1,71 @@
+package httpd
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/alash3al/redix/internals/config"
+)
+
+// authRequired whether the clients must authenticate or not, it is the case once a user is configured
+// or while the configs aren't loaded yet.
+func authRequired() bool {
+	cfg := config.Current()
+
+	return cfg == nil || len(cfg.Users) > 0
+}
+
+// authenticate returns the configured user having the specified credentials, nil if there is none
+func authenticate(username, password string) *config.UserConfig {
+	cfg := config.Current()
+	if cfg == nil {
+		return nil
+	}
+
+	return cfg.Authenticate(username, password)
+}
+
+// authorize authenticates the request using its basic authorization header if the authentication is required,
+// the request is replied with an error if its credentials are missing or invalid.
+func (r *request) authorize() bool {
+	if !authRequired() {
+		return true
+	}
+
+	username, password, ok := r.req.BasicAuth()
+	if ok && authenticate(username, password) != nil {
+		r.user = username
+		return true
+	}
+
+	r.Header().Set("WWW-Authenticate", `Basic realm="redix"`)
+	writeError(r, http.StatusUnauthorized, "NOAUTH Authentication required.")
+
+	return false
+}
+
+// allowed whether the specified user may reach the specified channel or not, any user may reach
+// any channel unless the authentication is required.
+func allowed(username string, channel string) bool {
+	if !authRequired() {
+		return true
+	}
+
+	cfg := config.Current()
+	if cfg == nil {
+		return false
+	}
+
+	user := cfg.User(username)
+	if user == nil {
+		return false
+	}
+
+	for _, pattern := range user.Channels {
+		if ok, _ := path.Match(pattern, channel); ok {
+			return true
+		}
+	}
+
+	return false
+}
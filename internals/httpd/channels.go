@@ -0,0 +1,135 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/stats"
+)
+
+// the interval of the comments sent to keep the idle streams alive
+const keepAliveInterval = 15 * time.Second
+
+// channel returns the full path of the specified channel, it is the same as the one used by PUBLISH and SUBSCRIBE
+func (r *request) channel(name string) []byte {
+	return r.key("redix/" + name)
+}
+
+// publish publishes the request body to the channel
+func (s *Server) publish(r *request) {
+	if !allowed(r.user, r.name) {
+		writeError(r, http.StatusForbidden, "NOPERM the user can't publish to this channel")
+		return
+	}
+
+	payload, err := ioutil.ReadAll(r.req.Body)
+	if err != nil {
+		writeError(r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.engine.Publish(r.channel(r.name), payload); err != nil {
+		writeError(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	r.WriteHeader(http.StatusNoContent)
+}
+
+// subscribe streams the messages published to the channel as Server-Sent Events,
+// the stream ends once the client disconnects or the server is shutting down.
+func (s *Server) subscribe(r *request) {
+	flusher, ok := r.ResponseWriter.(http.Flusher)
+	if !ok {
+		writeError(r, http.StatusInternalServerError, "streaming isn't supported")
+		return
+	}
+
+	if !allowed(r.user, r.name) {
+		writeError(r, http.StatusForbidden, "NOPERM the user can't subscribe to this channel")
+		return
+	}
+
+	channel := r.channel(r.name)
+
+	stats.ChannelSubscribed(string(channel))
+	defer stats.ChannelUnsubscribed(string(channel))
+
+	r.Header().Set("Content-Type", "text/event-stream")
+	r.Header().Set("Cache-Control", "no-cache")
+	r.WriteHeader(http.StatusOK)
+
+	// the messages are written by the engine subscriber, the response must not be written once the handler returns
+	var lock sync.Mutex
+	ended := false
+
+	defer (func() {
+		lock.Lock()
+		ended = true
+		lock.Unlock()
+	})()
+
+	write := func(format string, args ...interface{}) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if ended {
+			return fmt.Errorf("the stream has ended")
+		}
+
+		if _, err := fmt.Fprintf(r, format, args...); err != nil {
+			return err
+		}
+
+		flusher.Flush()
+
+		return nil
+	}
+
+	write(": subscribed to %s\n\n", r.name)
+
+	// the engine subscription ends once the stream ends
+	ctx, cancel := context.WithCancel(r.req.Context())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+
+	go (func() {
+		errChan <- contract.SubscribeContext(ctx, s.engine, channel, func(msg []byte) error {
+			return write("event: message\ndata: %s\n\n", eventData(msg))
+		})
+	})()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.req.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case err := <-errChan:
+			write("event: error\ndata: %s\n\n", eventData([]byte(err.Error())))
+			return
+		case <-keepAlive.C:
+			if write(": keep-alive\n\n") != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventData formats the specified message as the data of an event, each of its lines is a data field
+func eventData(msg []byte) []byte {
+	msg = bytes.ReplaceAll(msg, []byte("\r\n"), []byte("\n"))
+	msg = bytes.ReplaceAll(msg, []byte("\r"), []byte("\n"))
+
+	return bytes.ReplaceAll(msg, []byte("\n"), []byte("\ndata: "))
+}
@@ -0,0 +1,193 @@
+package httpd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/alash3al/redix/internals/config"
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/stats"
+)
+
+// the listing page sizes
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// TTLHeader the header holding the time to live of a key in milliseconds
+const TTLHeader = "X-Redix-TTL"
+
+// listItem represents a key of a listing page
+type listItem struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	TTL   int64  `json:"ttl"`
+}
+
+// listPage represents a listing page, the next page starts after the cursor, it is empty on the last page
+type listPage struct {
+	Keys   []*listItem `json:"keys"`
+	Cursor string      `json:"cursor"`
+}
+
+// get replies with the value of the key, its ttl is sent in the TTL header (-1 means it never expires)
+func (s *Server) get(r *request) {
+	ro, err := s.engine.Read(&contract.ReadInput{
+		Key: r.key(r.name),
+	})
+	if err != nil {
+		writeError(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !ro.Exists {
+		stats.KeyspaceMiss()
+		writeError(r, http.StatusNotFound, "key not found")
+		return
+	}
+
+	stats.KeyspaceHit()
+
+	r.Header().Set("Content-Type", "application/octet-stream")
+	r.Header().Set(TTLHeader, strconv.FormatInt(ttlOf(ro), 10))
+	r.WriteHeader(http.StatusOK)
+	r.Write(ro.Value)
+}
+
+// put sets the value of the key to the request body, the TTL header sets its time to live
+func (s *Server) put(r *request) {
+	if !r.writable() {
+		return
+	}
+
+	value, err := ioutil.ReadAll(r.req.Body)
+	if err != nil {
+		writeError(r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeOpts := &contract.WriteInput{
+		Key:   r.key(r.name),
+		Value: value,
+	}
+
+	if ttl := r.req.Header.Get(TTLHeader); ttl != "" {
+		n, err := strconv.ParseInt(ttl, 10, 64)
+		if err != nil || n < 1 {
+			writeError(r, http.StatusBadRequest, "invalid "+TTLHeader+" header")
+			return
+		}
+
+		writeOpts.TTL = time.Millisecond * time.Duration(n)
+	}
+
+	if cfg := config.Current(); cfg != nil && cfg.Server.Redis.AsyncWrites {
+		err = s.queue.Enqueue(writeOpts)
+	} else {
+		_, err = s.engine.Write(writeOpts)
+	}
+
+	if err != nil {
+		writeError(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	r.WriteHeader(http.StatusNoContent)
+}
+
+// delete deletes the key
+func (s *Server) delete(r *request) {
+	if !r.writable() {
+		return
+	}
+
+	ro, err := s.engine.Read(&contract.ReadInput{
+		Key:    r.key(r.name),
+		Delete: true,
+	})
+	if err != nil {
+		writeError(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if ro == nil || !ro.Exists {
+		writeError(r, http.StatusNotFound, "key not found")
+		return
+	}
+
+	r.WriteHeader(http.StatusNoContent)
+}
+
+// list replies with a page of the keys having the prefix query parameter sorted by their names,
+// the page starts after the cursor query parameter and has at most limit keys.
+func (s *Server) list(r *request) {
+	query := r.req.URL.Query()
+
+	limit := defaultPageSize
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeError(r, http.StatusBadRequest, "invalid limit, it must be between 1 and "+strconv.Itoa(maxPageSize))
+			return
+		}
+
+		limit = n
+	}
+
+	namespace := r.key("")
+	cursor := r.key(query.Get("cursor"))
+	hasCursor := query.Get("cursor") != ""
+
+	// the engines don't guarantee an order, so all the matching keys are collected then sorted
+	var result []*contract.ReadOutput
+
+	err := s.engine.Iterate(&contract.IteratorOpts{
+		Prefix: r.key(query.Get("prefix")),
+		Callback: func(ro *contract.ReadOutput) error {
+			if !hasCursor || bytes.Compare(ro.Key, cursor) > 0 {
+				result = append(result, ro)
+			}
+			return nil
+		},
+	})
+
+	if err != nil && err != contract.ErrStopIterator {
+		writeError(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Key, result[j].Key) < 0
+	})
+
+	page := &listPage{Keys: []*listItem{}}
+
+	for _, ro := range result {
+		if len(page.Keys) == limit {
+			page.Cursor = page.Keys[len(page.Keys)-1].Key
+			break
+		}
+
+		page.Keys = append(page.Keys, &listItem{
+			Key:   string(bytes.TrimPrefix(ro.Key, namespace)),
+			Value: string(ro.Value),
+			TTL:   ttlOf(ro),
+		})
+	}
+
+	writeJSON(r, http.StatusOK, page)
+}
+
+// ttlOf returns the remaining time to live of the specified key in milliseconds, -1 means it never expires
+func ttlOf(ro *contract.ReadOutput) int64 {
+	if ro.TTL == 0 {
+		return -1
+	}
+
+	return ro.TTL.Milliseconds()
+}
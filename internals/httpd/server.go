@@ -0,0 +1,223 @@
+// Package httpd implements the http interface, it exposes the keys as REST resources
+// and the pub/sub channels as Server-Sent Events streams.
+package httpd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alash3al/redix/internals/config"
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/metrics"
+	"github.com/alash3al/redix/internals/redis"
+	"github.com/alash3al/redix/internals/replication"
+	"github.com/alash3al/redix/internals/stats"
+	"github.com/alash3al/redix/internals/writebehind"
+	"github.com/gorilla/websocket"
+)
+
+// Server represents an http interface server
+type Server struct {
+	engine   contract.Engine
+	queue    *writebehind.Queue
+	srv      *http.Server
+	upgrader *websocket.Upgrader
+	secure   bool
+
+	// closing is closed once the server is shutting down, so the streams end
+	closing chan struct{}
+}
+
+// NewServer creates a new http server that serves the specified engine, the async writes are applied to the engine
+// through the specified write-behind queue, the listener uses the specified tls configs (the ones of the redis listener)
+// if not nil, so the clients authenticate the same way.
+func NewServer(cfg *config.HTTPConfig, tlsCfg *config.TLSConfig, engine contract.Engine, queue *writebehind.Queue) (*Server, error) {
+	s := &Server{
+		engine:  engine,
+		queue:   queue,
+		closing: make(chan struct{}),
+	}
+
+	s.upgrader = &websocket.Upgrader{
+		CheckOrigin: originChecker(cfg.AllowedOrigins),
+	}
+
+	s.srv = &http.Server{
+		Addr:    cfg.ListenAddr,
+		Handler: s,
+	}
+
+	if tlsCfg != nil {
+		tlsConfig, err := redis.NewTLSConfig(tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		s.srv.TLSConfig = tlsConfig
+		s.secure = true
+	}
+
+	s.srv.RegisterOnShutdown(func() {
+		close(s.closing)
+	})
+
+	return s, nil
+}
+
+// ListenAndServe starts the http server, it returns nil once the server is shutdown
+func (s *Server) ListenAndServe() error {
+	var err error
+
+	if s.secure {
+		err = s.srv.ListenAndServeTLS("", "")
+	} else {
+		err = s.srv.ListenAndServe()
+	}
+
+	if err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown gracefully shuts down the server, the streams are ended and the in-flight requests are awaited
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// ServeHTTP routes the requests of /db/{n}/keys[/{key}], /db/{n}/channels/{channel} and /db/{n}/ws
+func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 4)
+	if len(parts) < 3 || parts[0] != "db" {
+		writeError(res, http.StatusNotFound, "not found")
+		return
+	}
+
+	db, err := strconv.Atoi(parts[1])
+	if err != nil || db < 0 {
+		writeError(res, http.StatusBadRequest, "invalid db index")
+		return
+	}
+
+	r := &request{
+		ResponseWriter: res,
+		req:            req,
+		namespace:      fmt.Sprintf("/%d/", db),
+	}
+
+	if len(parts) > 3 {
+		r.name = parts[3]
+	}
+
+	// the websocket clients may authenticate once connected instead, as the browsers can't set the headers
+	if parts[2] == "ws" && r.name == "" && req.Method == http.MethodGet && req.Header.Get("Authorization") == "" {
+		s.gateway(r)
+		return
+	}
+
+	if !r.authorize() {
+		return
+	}
+
+	var handler func(*request)
+	var operation string
+
+	switch {
+	case parts[2] == "keys" && r.name == "" && req.Method == http.MethodGet:
+		handler, operation = s.list, "list"
+	case parts[2] == "keys" && r.name != "" && req.Method == http.MethodGet:
+		handler, operation = s.get, "get"
+	case parts[2] == "keys" && r.name != "" && req.Method == http.MethodPut:
+		handler, operation = s.put, "put"
+	case parts[2] == "keys" && r.name != "" && req.Method == http.MethodDelete:
+		handler, operation = s.delete, "delete"
+	case parts[2] == "channels" && r.name != "" && req.Method == http.MethodPost:
+		handler, operation = s.publish, "publish"
+	case parts[2] == "channels" && r.name != "" && req.Method == http.MethodGet:
+		// the streams are long living, so they aren't measured
+		s.subscribe(r)
+		return
+	case parts[2] == "ws" && r.name == "" && req.Method == http.MethodGet:
+		s.gateway(r)
+		return
+	case parts[2] == "keys" || parts[2] == "channels" || parts[2] == "ws":
+		writeError(res, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	default:
+		writeError(res, http.StatusNotFound, "not found")
+		return
+	}
+
+	start := time.Now()
+
+	handler(r)
+
+	elapsed := time.Since(start)
+
+	stats.CommandProcessed("http."+operation, elapsed, r.failed)
+	metrics.ObserveCommand("http."+operation, elapsed)
+}
+
+// request represents an http request targeting a key or a channel of a db
+type request struct {
+	http.ResponseWriter
+
+	req *http.Request
+
+	// namespace the namespace of the requested db
+	namespace string
+
+	// name the key or the channel name
+	name string
+
+	// user the name of the authenticated user, empty unless the authentication is required
+	user string
+
+	failed bool
+}
+
+// key returns the full path of the specified key relative to the db namespace
+func (r *request) key(k string) []byte {
+	return []byte(r.namespace + strings.TrimLeft(k, "/"))
+}
+
+// WriteHeader records the failed requests then sends the status code,
+// a missing key isn't a failure, it is the equivalent of a nil reply.
+func (r *request) WriteHeader(status int) {
+	r.failed = status >= http.StatusBadRequest && status != http.StatusNotFound
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// writable reports whether the writes are allowed or not, the request is replied with an error if not
+func (r *request) writable() bool {
+	if replication.IsReplica() {
+		writeError(r, http.StatusForbidden, "READONLY You can't write against a read only replica.")
+		return false
+	}
+
+	if cfg := config.Current(); cfg != nil && cfg.Server.Redis.ReadOnly {
+		writeError(r, http.StatusForbidden, "READONLY You can't write against a read only server.")
+		return false
+	}
+
+	return true
+}
+
+// writeJSON replies with the specified value encoded as json
+func writeJSON(res http.ResponseWriter, status int, v interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+
+	json.NewEncoder(res).Encode(v)
+}
+
+// writeError replies with the specified error message
+func writeError(res http.ResponseWriter, status int, msg string) {
+	writeJSON(res, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,305 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/stats"
+	"github.com/gorilla/websocket"
+)
+
+// the websocket connections settings
+const (
+	wsPingInterval = 15 * time.Second
+	wsWriteTimeout = 10 * time.Second
+)
+
+var errPatternsUnsupported = errors.New("the patterns aren't supported by the engine")
+
+// wsRequest represents a message sent by a websocket client,
+// i.e {"action": "subscribe", "channels": ["news", "alerts"]}
+type wsRequest struct {
+	Action   string   `json:"action"`
+	Channels []string `json:"channels"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+}
+
+// wsConn represents a websocket client and its subscriptions,
+// each subscription is ended by canceling its context.
+type wsConn struct {
+	*websocket.Conn
+
+	server        *Server
+	req           *request
+	ctx           context.Context
+	cancel        context.CancelFunc
+	subscriptions map[string]context.CancelFunc
+	patterns      map[string]context.CancelFunc
+
+	// writeLock a websocket connection supports one writer at a time
+	writeLock sync.Mutex
+}
+
+// originChecker returns the function that checks the origin of the websocket requests,
+// the same origin is required unless the origin is one of the allowed ones ("*" allows any).
+func originChecker(allowed []string) func(*http.Request) bool {
+	if len(allowed) < 1 {
+		return nil
+	}
+
+	return func(req *http.Request) bool {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		for _, o := range allowed {
+			if o == "*" || o == origin {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// gateway upgrades the request to a websocket connection the client subscribes through to the channels of the db,
+// the client sends {"action": "subscribe" | "unsubscribe" | "psubscribe" | "punsubscribe", "channels": [...]}
+// and receives the events as json, it must send {"action": "auth", "username": "...", "password": "..."} first
+// if the authentication is required and the upgrade request wasn't authenticated.
+func (s *Server) gateway(r *request) {
+	conn, err := s.upgrader.Upgrade(r.ResponseWriter, r.req, nil)
+	if err != nil {
+		// the upgrader has already replied with the error
+		return
+	}
+
+	c := &wsConn{
+		Conn:          conn,
+		server:        s,
+		req:           r,
+		subscriptions: map[string]context.CancelFunc{},
+		patterns:      map[string]context.CancelFunc{},
+	}
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	defer c.close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go (func() {
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-s.closing:
+				c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "the server is shutting down"), time.Now().Add(wsWriteTimeout))
+				c.Close()
+				return
+			case <-ping.C:
+				if c.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)) != nil {
+					c.Close()
+					return
+				}
+			}
+		}
+	})()
+
+	for {
+		var msg wsRequest
+
+		if err := c.ReadJSON(&msg); err != nil {
+			if !isJSONError(err) {
+				return
+			}
+
+			c.send(map[string]interface{}{"type": "error", "error": "invalid message: " + err.Error()})
+			continue
+		}
+
+		if msg.Action == "auth" {
+			c.auth(msg.Username, msg.Password)
+			continue
+		}
+
+		if authRequired() && r.user == "" {
+			c.send(map[string]interface{}{"type": "error", "error": "NOAUTH Authentication required."})
+			continue
+		}
+
+		switch msg.Action {
+		case "subscribe":
+			for _, channel := range msg.Channels {
+				c.subscribe(channel)
+			}
+		case "unsubscribe":
+			for _, channel := range orAll(msg.Channels, c.subscriptions) {
+				c.unsubscribe(channel)
+			}
+		case "psubscribe":
+			for _, pattern := range msg.Channels {
+				c.psubscribe(pattern)
+			}
+		case "punsubscribe":
+			for _, pattern := range orAll(msg.Channels, c.patterns) {
+				c.punsubscribe(pattern)
+			}
+		default:
+			c.send(map[string]interface{}{"type": "error", "error": "unknown action (" + msg.Action + ")"})
+		}
+	}
+}
+
+// auth authenticates the client using the specified credentials
+func (c *wsConn) auth(username, password string) {
+	if authenticate(username, password) == nil {
+		c.send(map[string]interface{}{"type": "error", "error": "WRONGPASS invalid username-password pair or user is disabled."})
+		return
+	}
+
+	c.req.user = username
+
+	c.send(map[string]interface{}{"type": "auth", "username": username})
+}
+
+// subscribe subscribes the client to the specified channel if its user may reach it
+func (c *wsConn) subscribe(channel string) {
+	if !allowed(c.req.user, channel) {
+		c.send(map[string]interface{}{"type": "error", "channel": channel, "error": "NOPERM the user can't subscribe to this channel"})
+		return
+	}
+
+	if _, exists := c.subscriptions[channel]; !exists {
+		ctx, cancel := context.WithCancel(c.ctx)
+		c.subscriptions[channel] = cancel
+
+		stats.ChannelSubscribed(string(c.req.channel(channel)))
+
+		go (func() {
+			err := contract.SubscribeContext(ctx, c.server.engine, c.req.channel(channel), func(msg []byte) error {
+				return c.send(map[string]interface{}{"type": "message", "channel": channel, "data": string(msg)})
+			})
+
+			if err != nil && ctx.Err() == nil {
+				c.send(map[string]interface{}{"type": "error", "channel": channel, "error": err.Error()})
+			}
+		})()
+	}
+
+	c.send(map[string]interface{}{"type": "subscribe", "channel": channel, "count": c.count()})
+}
+
+// unsubscribe unsubscribes the client from the specified channel
+func (c *wsConn) unsubscribe(channel string) {
+	if cancel, exists := c.subscriptions[channel]; exists {
+		cancel()
+		delete(c.subscriptions, channel)
+
+		stats.ChannelUnsubscribed(string(c.req.channel(channel)))
+	}
+
+	c.send(map[string]interface{}{"type": "unsubscribe", "channel": channel, "count": c.count()})
+}
+
+// psubscribe subscribes the client to the channels matching the specified pattern (as path.Match),
+// the messages of the matched channels its user can't reach are skipped.
+func (c *wsConn) psubscribe(pattern string) {
+	if _, exists := c.patterns[pattern]; !exists {
+		ctx, cancel := context.WithCancel(c.ctx)
+		c.patterns[pattern] = cancel
+
+		namespace, user := c.req.channel(""), c.req.user
+
+		go (func() {
+			err := contract.PSubscribe(ctx, c.server.engine, c.req.channel(pattern), func(channel, msg []byte) error {
+				name := string(bytes.TrimPrefix(channel, namespace))
+				if !allowed(user, name) {
+					return nil
+				}
+
+				return c.send(map[string]interface{}{"type": "pmessage", "pattern": pattern, "channel": name, "data": string(msg)})
+			})
+
+			if err == contract.ErrPatternsUnsupported {
+				err = errPatternsUnsupported
+			}
+
+			if err != nil && ctx.Err() == nil {
+				c.send(map[string]interface{}{"type": "error", "pattern": pattern, "error": err.Error()})
+			}
+		})()
+	}
+
+	c.send(map[string]interface{}{"type": "psubscribe", "pattern": pattern, "count": c.count()})
+}
+
+// punsubscribe unsubscribes the client from the specified pattern
+func (c *wsConn) punsubscribe(pattern string) {
+	if cancel, exists := c.patterns[pattern]; exists {
+		cancel()
+		delete(c.patterns, pattern)
+	}
+
+	c.send(map[string]interface{}{"type": "punsubscribe", "pattern": pattern, "count": c.count()})
+}
+
+// count returns the number of the client subscriptions (channels and patterns)
+func (c *wsConn) count() int {
+	return len(c.subscriptions) + len(c.patterns)
+}
+
+// send sends the specified event to the client
+func (c *wsConn) send(event map[string]interface{}) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+	return c.WriteJSON(event)
+}
+
+// close ends all the subscriptions then closes the connection
+func (c *wsConn) close() {
+	c.cancel()
+
+	for channel := range c.subscriptions {
+		stats.ChannelUnsubscribed(string(c.req.channel(channel)))
+	}
+
+	c.Close()
+}
+
+// orAll returns the specified names, or all the names of the specified subscriptions if there are none
+func orAll(names []string, subscriptions map[string]context.CancelFunc) []string {
+	if len(names) > 0 {
+		return names
+	}
+
+	for name := range subscriptions {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// isJSONError whether the specified error is a json decoding error or not
+func isJSONError(err error) bool {
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return true
+	}
+
+	return false
+}
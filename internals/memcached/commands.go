@@ -0,0 +1,412 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alash3al/redix/internals/config"
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/redis/commands"
+	"github.com/alash3al/redix/internals/replication"
+	"github.com/alash3al/redix/internals/stats"
+)
+
+// handlers the supported commands handlers, an error is returned if the connection became unusable
+var handlers = map[string]func(*Server, *command) error{
+	"get":     (*Server).get,
+	"gets":    (*Server).get,
+	"set":     (*Server).storage,
+	"add":     (*Server).storage,
+	"replace": (*Server).storage,
+	"append":  (*Server).storage,
+	"prepend": (*Server).storage,
+	"cas":     (*Server).storage,
+	"delete":  (*Server).delete,
+	"incr":    (*Server).incr,
+	"decr":    (*Server).incr,
+	"touch":   (*Server).touch,
+	"version": (*Server).version,
+}
+
+var errNonNumeric = errors.New("cannot increment or decrement non-numeric value")
+
+// command represents a received command
+type command struct {
+	name    string
+	args    []string
+	noreply bool
+	failed  bool
+
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// reply writes the specified reply line unless the client asked for no reply
+func (c *command) reply(line string) {
+	if !c.noreply {
+		c.w.WriteString(line + "\r\n")
+	}
+}
+
+// clientError replies with the specified client error, the errors are always sent
+func (c *command) clientError(msg string) {
+	c.failed = true
+	c.w.WriteString("CLIENT_ERROR " + msg + "\r\n")
+}
+
+// serverError replies with the specified server error, the errors are always sent
+func (c *command) serverError(msg string) {
+	c.failed = true
+	c.w.WriteString("SERVER_ERROR " + msg + "\r\n")
+}
+
+// result replies with the specified reply line or error, the errors are always sent
+func (c *command) result(line string, err error) {
+	switch err {
+	case nil:
+		c.reply(line)
+	case errNonNumeric:
+		c.clientError(err.Error())
+	case contract.ErrTransactionConflict:
+		c.serverError("the write conflicted with concurrent ones, retry it")
+	default:
+		c.serverError(err.Error())
+	}
+}
+
+// parseNoReply removes the trailing noreply argument if exists
+func (c *command) parseNoReply() {
+	if n := len(c.args); n > 0 && c.args[n-1] == "noreply" {
+		c.noreply = true
+		c.args = c.args[:n-1]
+	}
+}
+
+// get implements get|gets <key>*
+func (s *Server) get(c *command) error {
+	if len(c.args) < 1 {
+		c.w.WriteString("ERROR\r\n")
+		c.failed = true
+		return nil
+	}
+
+	for _, key := range c.args {
+		if !validKey(key) {
+			c.clientError("bad command line format")
+			return nil
+		}
+	}
+
+	// the memcached writes of this node run exclusively unless the engine supports transactions
+	defer commands.Lock(false)()
+
+	for _, key := range c.args {
+		it, err := s.load(s.engine, key)
+		if err != nil {
+			c.serverError(err.Error())
+			return nil
+		}
+
+		if !it.exists {
+			stats.KeyspaceMiss()
+			continue
+		}
+
+		stats.KeyspaceHit()
+
+		c.w.WriteString("VALUE " + key + " " + strconv.FormatUint(uint64(it.flags), 10) + " " + strconv.Itoa(len(it.value)))
+		if c.name == "gets" {
+			c.w.WriteString(" " + strconv.FormatUint(it.cas(), 10))
+		}
+		c.w.WriteString("\r\n")
+		c.w.Write(it.value)
+		c.w.WriteString("\r\n")
+	}
+
+	c.w.WriteString("END\r\n")
+
+	return nil
+}
+
+// storage implements set|add|replace|append|prepend <key> <flags> <exptime> <bytes> [noreply]
+// and cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+func (s *Server) storage(c *command) error {
+	c.parseNoReply()
+
+	argc := 4
+	if c.name == "cas" {
+		argc = 5
+	}
+
+	if len(c.args) != argc {
+		c.w.WriteString("ERROR\r\n")
+		c.failed = true
+		return nil
+	}
+
+	key := c.args[0]
+	flags, flagsErr := strconv.ParseUint(c.args[1], 10, 32)
+	exptime, exptimeErr := strconv.ParseInt(c.args[2], 10, 64)
+	size, sizeErr := strconv.Atoi(c.args[3])
+
+	var unique uint64
+	var uniqueErr error
+	if c.name == "cas" {
+		unique, uniqueErr = strconv.ParseUint(c.args[4], 10, 64)
+	}
+
+	if sizeErr != nil || size < 0 {
+		// the data block can't be skipped without its size
+		c.clientError("bad command line format")
+		return nil
+	}
+
+	// the data block is always consumed, so the next command can be read
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return err
+	}
+
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		c.clientError("bad data chunk")
+		return nil
+	}
+
+	data = data[:size]
+
+	switch {
+	case !validKey(key) || flagsErr != nil || exptimeErr != nil || uniqueErr != nil:
+		c.clientError("bad command line format")
+		return nil
+	case size > maxItemSize:
+		c.serverError("object too large for cache")
+		return nil
+	case !writable(c):
+		return nil
+	}
+
+	ttl, expired := expiration(exptime)
+
+	c.result(s.atomically(func(engine contract.Engine) (string, error) {
+		current, err := s.load(engine, key)
+		if err != nil {
+			return "", err
+		}
+
+		it := &item{value: data, flags: uint32(flags), version: current.version}
+
+		switch c.name {
+		case "add":
+			if current.exists {
+				return "NOT_STORED", nil
+			}
+		case "replace":
+			if !current.exists {
+				return "NOT_STORED", nil
+			}
+		case "append", "prepend":
+			if !current.exists {
+				return "NOT_STORED", nil
+			}
+
+			// the flags and the exptime of the item are kept
+			if c.name == "append" {
+				it.value = append(append([]byte(nil), current.value...), data...)
+			} else {
+				it.value = append(append([]byte(nil), data...), current.value...)
+			}
+
+			it.flags, it.ttl = current.flags, current.ttl
+
+			return "STORED", s.store(engine, key, it)
+		case "cas":
+			if !current.exists {
+				return "NOT_FOUND", nil
+			}
+
+			if current.cas() != unique {
+				return "EXISTS", nil
+			}
+		}
+
+		if expired {
+			// an already expired item is never seen, so it just replaces the current one
+			_, err := s.remove(engine, key)
+			return "STORED", err
+		}
+
+		it.ttl = ttl
+
+		return "STORED", s.store(engine, key, it)
+	}))
+
+	return nil
+}
+
+// delete implements delete <key> [0] [noreply]
+func (s *Server) delete(c *command) error {
+	c.parseNoReply()
+
+	// the legacy clients send a zero hold time
+	if len(c.args) == 2 && c.args[1] == "0" {
+		c.args = c.args[:1]
+	}
+
+	if len(c.args) != 1 || !validKey(c.args[0]) {
+		c.clientError("bad command line format.  Usage: delete <key> [noreply]")
+		return nil
+	}
+
+	if !writable(c) {
+		return nil
+	}
+
+	c.result(s.atomically(func(engine contract.Engine) (string, error) {
+		deleted, err := s.remove(engine, c.args[0])
+		if err != nil || !deleted {
+			return "NOT_FOUND", err
+		}
+
+		return "DELETED", nil
+	}))
+
+	return nil
+}
+
+// incr implements incr|decr <key> <value> [noreply], the incr wraps around at 64 bits while the decr stops at 0
+func (s *Server) incr(c *command) error {
+	c.parseNoReply()
+
+	if len(c.args) != 2 || !validKey(c.args[0]) {
+		c.w.WriteString("ERROR\r\n")
+		c.failed = true
+		return nil
+	}
+
+	delta, err := strconv.ParseUint(c.args[1], 10, 64)
+	if err != nil {
+		c.clientError("invalid numeric delta argument")
+		return nil
+	}
+
+	if !writable(c) {
+		return nil
+	}
+
+	c.result(s.atomically(func(engine contract.Engine) (string, error) {
+		it, err := s.load(engine, c.args[0])
+		if err != nil {
+			return "", err
+		}
+
+		if !it.exists {
+			return "NOT_FOUND", nil
+		}
+
+		n, err := strconv.ParseUint(strings.TrimSpace(string(it.value)), 10, 64)
+		if err != nil {
+			return "", errNonNumeric
+		}
+
+		switch {
+		case c.name == "incr":
+			n += delta
+		case delta > n:
+			n = 0
+		default:
+			n -= delta
+		}
+
+		it.value = []byte(strconv.FormatUint(n, 10))
+
+		return string(it.value), s.store(engine, c.args[0], it)
+	}))
+
+	return nil
+}
+
+// touch implements touch <key> <exptime> [noreply]
+func (s *Server) touch(c *command) error {
+	c.parseNoReply()
+
+	if len(c.args) != 2 || !validKey(c.args[0]) {
+		c.w.WriteString("ERROR\r\n")
+		c.failed = true
+		return nil
+	}
+
+	exptime, err := strconv.ParseInt(c.args[1], 10, 64)
+	if err != nil {
+		c.clientError("invalid exptime argument")
+		return nil
+	}
+
+	if !writable(c) {
+		return nil
+	}
+
+	ttl, expired := expiration(exptime)
+
+	c.result(s.atomically(func(engine contract.Engine) (string, error) {
+		it, err := s.load(engine, c.args[0])
+		if err != nil {
+			return "", err
+		}
+
+		if !it.exists {
+			return "NOT_FOUND", nil
+		}
+
+		if expired {
+			_, err = s.remove(engine, c.args[0])
+		} else {
+			it.ttl = ttl
+			err = s.store(engine, c.args[0], it)
+		}
+
+		return "TOUCHED", err
+	}))
+
+	return nil
+}
+
+// version implements version
+func (s *Server) version(c *command) error {
+	c.w.WriteString("VERSION redix\r\n")
+
+	return nil
+}
+
+// writable reports whether the writes are allowed or not, the command is replied with an error if not
+func writable(c *command) bool {
+	if replication.IsReplica() {
+		c.serverError("READONLY You can't write against a read only replica.")
+		return false
+	}
+
+	if cfg := config.Current(); cfg != nil && cfg.Server.Redis.ReadOnly {
+		c.serverError("READONLY You can't write against a read only server.")
+		return false
+	}
+
+	return true
+}
+
+// validKey whether the specified key is a valid memcached key or not
+func validKey(key string) bool {
+	if len(key) < 1 || len(key) > maxKeyLen {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7F {
+			return false
+		}
+	}
+
+	return true
+}
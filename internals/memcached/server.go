@@ -0,0 +1,221 @@
+// Package memcached implements the memcached text protocol interface
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alash3al/redix/internals/config"
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/metrics"
+	"github.com/alash3al/redix/internals/stats"
+)
+
+// the protocol limits
+const (
+	maxLineLen  = 2048
+	maxKeyLen   = 250
+	maxItemSize = 1 << 20
+)
+
+// Server represents a memcached interface server
+type Server struct {
+	engine    contract.Engine
+	addr      string
+	namespace string
+	listener  net.Listener
+	conns     map[net.Conn]struct{}
+
+	inflight     sync.WaitGroup
+	shuttingDown bool
+	sync.Mutex
+}
+
+// NewServer creates a new memcached server that serves the specified engine,
+// the keys are stored in the db configured for the memcached interface.
+func NewServer(cfg *config.MemcachedConfig, engine contract.Engine) *Server {
+	return &Server{
+		engine:    engine,
+		addr:      cfg.ListenAddr,
+		namespace: fmt.Sprintf("/%d/", cfg.DB),
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+// ListenAndServe starts the memcached server, it returns nil once the server is shutdown
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.listener = ln
+	s.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.Lock()
+			shuttingDown := s.shuttingDown
+			s.Unlock()
+
+			if shuttingDown {
+				return nil
+			}
+
+			return err
+		}
+
+		s.Lock()
+		s.conns[conn] = struct{}{}
+		s.Unlock()
+
+		go s.serve(conn)
+	}
+}
+
+// Shutdown gracefully shuts down the server, it stops accepting new connections,
+// waits for the in-flight commands to finish then disconnects all the clients.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.Lock()
+	s.shuttingDown = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	s.Unlock()
+
+	done := make(chan struct{})
+
+	go (func() {
+		s.inflight.Wait()
+		close(done)
+	})()
+
+	var err error
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.Unlock()
+
+	return err
+}
+
+// serve handles the commands of the specified connection until it is closed
+func (s *Server) serve(conn net.Conn) {
+	defer (func() {
+		s.Lock()
+		delete(s.conns, conn)
+		s.Unlock()
+
+		conn.Close()
+	})()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		line, err := readLine(r)
+		if err == errLineTooLong {
+			w.WriteString("CLIENT_ERROR line too long\r\n")
+			w.Flush()
+			return
+		}
+
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Println("[ERROR] memcached connection failed due to:", err.Error())
+			}
+			return
+		}
+
+		args := strings.Fields(line)
+		if len(args) < 1 {
+			w.WriteString("ERROR\r\n")
+			w.Flush()
+			continue
+		}
+
+		name := strings.ToLower(args[0])
+		if name == "quit" {
+			return
+		}
+
+		handler, exists := handlers[name]
+		if !exists {
+			w.WriteString("ERROR\r\n")
+			w.Flush()
+			continue
+		}
+
+		s.Lock()
+		if s.shuttingDown {
+			s.Unlock()
+			w.WriteString("SERVER_ERROR the server is shutting down\r\n")
+			w.Flush()
+			return
+		}
+		s.inflight.Add(1)
+		s.Unlock()
+
+		start := time.Now()
+
+		c := &command{name: name, args: args[1:], r: r, w: w}
+		err = handler(s, c)
+
+		s.inflight.Done()
+
+		if err != nil {
+			// the connection is unusable once the data block couldn't be read
+			return
+		}
+
+		elapsed := time.Since(start)
+
+		stats.CommandProcessed("memcached."+name, elapsed, c.failed)
+		metrics.ObserveCommand("memcached."+name, elapsed)
+
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+var errLineTooLong = fmt.Errorf("line too long")
+
+// readLine reads a command line without its line ending
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+
+		line = append(line, chunk...)
+		if len(line) > maxLineLen {
+			return "", errLineTooLong
+		}
+
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package memcached
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/redis/commands"
+)
+
+// the longest relative exptime, the larger ones are unix timestamps
+const maxRelativeExptime = 60 * 60 * 24 * 30
+
+// the read-modify-writes settings
+const (
+	// maxConflictRetries how many times a write conflicting with a concurrent one is retried
+	maxConflictRetries = 3
+)
+
+// item represents a memcached item
+type item struct {
+	value   []byte
+	flags   uint32
+	version uint64
+	ttl     time.Duration
+	exists  bool
+}
+
+// cas returns the cas unique of the item, it is the version stamped by its latest memcached write,
+// or a hash of its flags and value if it has been written by another interface since then.
+func (it *item) cas() uint64 {
+	if it.version != 0 {
+		return it.version
+	}
+
+	h := fnv.New64a()
+
+	var flags [4]byte
+	binary.BigEndian.PutUint32(flags[:], it.flags)
+
+	h.Write(flags[:])
+	h.Write(it.value)
+
+	// zero isn't a valid cas unique
+	if sum := h.Sum64(); sum != 0 {
+		return sum
+	}
+
+	return 1
+}
+
+// nextVersion returns the version stamped by the next write of the item, it is taken from the clock
+// and always greater than the current one, so a value that is changed then changed back gets a new cas unique,
+// even if the item has been deleted in between.
+func (it *item) nextVersion() uint64 {
+	version := uint64(time.Now().UnixNano())
+	if version <= it.version {
+		version = it.version + 1
+	}
+
+	return version
+}
+
+// dataKey returns the engine key holding the value of the specified key, it is the same key the redis commands use
+func (s *Server) dataKey(key string) []byte {
+	return []byte(s.namespace + strings.TrimLeft(key, "/"))
+}
+
+// metaKey returns the engine key holding the flags and the version of the specified key, they are stored
+// along with a checksum of the value so they are ignored once the value is changed by another interface.
+func (s *Server) metaKey(key string) []byte {
+	return []byte("/memcached" + string(s.dataKey(key)))
+}
+
+// load reads the specified item using the specified engine (or transaction)
+func (s *Server) load(engine contract.Engine, key string) (*item, error) {
+	ro, err := engine.Read(&contract.ReadInput{
+		Key: s.dataKey(key),
+	})
+	if err != nil || !ro.Exists {
+		return &item{}, err
+	}
+
+	it := &item{value: ro.Value, ttl: ro.TTL, exists: true}
+
+	meta, err := engine.Read(&contract.ReadInput{
+		Key: s.metaKey(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the metas stored before the versions were introduced have no version
+	if !meta.Exists || (len(meta.Value) != 12 && len(meta.Value) != 20) {
+		return it, nil
+	}
+
+	if binary.BigEndian.Uint64(meta.Value[4:12]) != checksum(it.value) {
+		return it, nil
+	}
+
+	it.flags = binary.BigEndian.Uint32(meta.Value[:4])
+
+	if len(meta.Value) == 20 {
+		it.version = binary.BigEndian.Uint64(meta.Value[12:])
+	}
+
+	return it, nil
+}
+
+// store writes the specified item using the specified engine (or transaction),
+// the item is stamped with a new version greater than its current one.
+func (s *Server) store(engine contract.Engine, key string, it *item) error {
+	if _, err := engine.Write(&contract.WriteInput{
+		Key:   s.dataKey(key),
+		Value: it.value,
+		TTL:   it.ttl,
+	}); err != nil {
+		return err
+	}
+
+	it.version = it.nextVersion()
+
+	meta := make([]byte, 20)
+	binary.BigEndian.PutUint32(meta[:4], it.flags)
+	binary.BigEndian.PutUint64(meta[4:12], checksum(it.value))
+	binary.BigEndian.PutUint64(meta[12:], it.version)
+
+	_, err := engine.Write(&contract.WriteInput{
+		Key:   s.metaKey(key),
+		Value: meta,
+		TTL:   it.ttl,
+	})
+
+	return err
+}
+
+// remove deletes the specified item using the specified engine (or transaction),
+// it reports whether the item existed or not.
+func (s *Server) remove(engine contract.Engine, key string) (bool, error) {
+	ro, err := engine.Read(&contract.ReadInput{
+		Key:    s.dataKey(key),
+		Delete: true,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := engine.Read(&contract.ReadInput{
+		Key:    s.metaKey(key),
+		Delete: true,
+	}); err != nil {
+		return false, err
+	}
+
+	return ro != nil && ro.Exists, nil
+}
+
+// atomically runs the specified read-modify-write within an engine transaction if the engine supports them,
+// otherwise while no redis command touching the data is running (as an exclusive command), so the concurrent writes
+// of this node can't interleave with it, the transactions conflicting with concurrent writes are retried.
+func (s *Server) atomically(fn func(contract.Engine) (string, error)) (reply string, err error) {
+	for attempt := 0; attempt <= maxConflictRetries; attempt++ {
+		if reply, err = s.attempt(fn); err != contract.ErrTransactionConflict {
+			return reply, err
+		}
+	}
+
+	return reply, err
+}
+
+// attempt runs the specified read-modify-write once, see atomically
+func (s *Server) attempt(fn func(contract.Engine) (string, error)) (string, error) {
+	transactor, ok := s.engine.(contract.Transactor)
+	if !ok {
+		defer commands.Lock(true)()
+		return fn(s.engine)
+	}
+
+	tx, err := transactor.Begin()
+	if err == contract.ErrTransactionUnsupported {
+		defer commands.Lock(true)()
+		return fn(s.engine)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	defer commands.Lock(false)()
+
+	reply, err := fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return reply, nil
+}
+
+// expiration converts the specified memcached exptime to a time to live,
+// 0 means it never expires, a negative one or a past unix timestamp means it has already expired.
+func expiration(exptime int64) (time.Duration, bool) {
+	switch {
+	case exptime == 0:
+		return 0, false
+	case exptime < 0:
+		return 0, true
+	case exptime <= maxRelativeExptime:
+		return time.Duration(exptime) * time.Second, false
+	}
+
+	ttl := time.Until(time.Unix(exptime, 0))
+	if ttl <= 0 {
+		return 0, true
+	}
+
+	return ttl, false
+}
+
+// checksum returns the checksum of the specified value
+func checksum(value []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(value)
+
+	return h.Sum64()
+}
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// Engine wraps a contract.Engine to record its operations latencies and errors
+type Engine struct {
+	contract.Engine
+}
+
+// batchEngine an instrumented Engine for the engines that support batch writes
+type batchEngine struct {
+	*Engine
+	batchWriter contract.BatchWriter
+}
+
+// InstrumentEngine wraps the specified engine with an instrumented one,
+// the optional engine interfaces are kept available.
+func InstrumentEngine(engine contract.Engine) contract.Engine {
+	instrumented := &Engine{Engine: engine}
+
+	if batchWriter, ok := engine.(contract.BatchWriter); ok {
+		return &batchEngine{Engine: instrumented, batchWriter: batchWriter}
+	}
+
+	return instrumented
+}
+
+// Write writes into the underlying engine
+func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	defer observe("write", time.Now())
+
+	ret, err := e.Engine.Write(input)
+	if err != nil {
+		EngineErrors.Inc("write")
+	}
+
+	return ret, err
+}
+
+// Read reads from the underlying engine
+func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	defer observe("read", time.Now())
+
+	ret, err := e.Engine.Read(input)
+	if err != nil {
+		EngineErrors.Inc("read")
+	}
+
+	return ret, err
+}
+
+// Iterate iterates over the underlying engine
+func (e *Engine) Iterate(opts *contract.IteratorOpts) error {
+	defer observe("iterate", time.Now())
+
+	err := e.Engine.Iterate(opts)
+	if err != nil && err != contract.ErrStopIterator {
+		EngineErrors.Inc("iterate")
+	}
+
+	return err
+}
+
+// Publish publishes using the underlying engine
+func (e *Engine) Publish(channel []byte, payload []byte) error {
+	defer observe("publish", time.Now())
+
+	err := e.Engine.Publish(channel, payload)
+	if err != nil {
+		EngineErrors.Inc("publish")
+	}
+
+	return err
+}
+
+// SubscribeContext subscribes using the underlying engine till the context is done
+func (e *Engine) SubscribeContext(ctx context.Context, channel []byte, cb func([]byte) error) error {
+	return contract.SubscribeContext(ctx, e.Engine, channel, cb)
+}
+
+// PSubscribe subscribes to the channels matching the pattern using the underlying engine
+func (e *Engine) PSubscribe(ctx context.Context, pattern []byte, cb func([]byte, []byte) error) error {
+	return contract.PSubscribe(ctx, e.Engine, pattern, cb)
+}
+
+// Stats reports the stats of the underlying engine if it supports that
+func (e *Engine) Stats() (map[string]interface{}, error) {
+	if reporter, ok := e.Engine.(contract.Reporter); ok {
+		return reporter.Stats()
+	}
+
+	return nil, nil
+}
+
+// Keyspace reports the keyspace of the underlying engine if it supports that
+func (e *Engine) Keyspace() (map[int]*contract.KeyspaceStats, error) {
+	if reporter, ok := e.Engine.(contract.Reporter); ok {
+		return reporter.Keyspace()
+	}
+
+	return nil, nil
+}
+
+// transaction an instrumented contract.Transaction
+type transaction struct {
+	*Engine
+	tx contract.Transaction
+}
+
+// Begin starts a new transaction on the underlying engine if it supports that
+func (e *Engine) Begin() (contract.Transaction, error) {
+	transactor, ok := e.Engine.(contract.Transactor)
+	if !ok {
+		return nil, contract.ErrTransactionUnsupported
+	}
+
+	tx, err := transactor.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{Engine: &Engine{Engine: tx}, tx: tx}, nil
+}
+
+// Commit commits the underlying transaction
+func (t *transaction) Commit() error {
+	defer observe("commit", time.Now())
+
+	err := t.tx.Commit()
+	if err != nil {
+		EngineErrors.Inc("commit")
+	}
+
+	return err
+}
+
+// Rollback rolls the underlying transaction back
+func (t *transaction) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// observe records the duration of the specified operation
+func observe(operation string, start time.Time) {
+	EngineDuration.Observe(operation, time.Since(start).Seconds())
+}
+
+// WriteBatch writes the specified inputs into the underlying engine at once
+func (e *batchEngine) WriteBatch(inputs []*contract.WriteInput) error {
+	defer observe("write_batch", time.Now())
+
+	err := e.batchWriter.WriteBatch(inputs)
+	if err != nil {
+		EngineErrors.Inc("write_batch")
+	}
+
+	return err
+}
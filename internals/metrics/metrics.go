@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alash3al/redix/internals/stats"
+)
+
+// global metrics
+var (
+	Connections     = &Gauge{}
+	CommandDuration = NewHistogramVec(DefaultBuckets)
+	EngineDuration  = NewHistogramVec(DefaultBuckets)
+	EngineErrors    = NewCounterVec()
+
+	WriteBehindDepth    = &Gauge{}
+	WriteBehindWrites   = &Counter{}
+	WriteBehindBatches  = &Counter{}
+	WriteBehindFailures = &Counter{}
+)
+
+// ObserveCommand records the duration of an executed command
+func ObserveCommand(name string, d time.Duration) {
+	CommandDuration.Observe(name, d.Seconds())
+}
+
+// ListenAndServe starts the metrics http server on the specified address and path
+func ListenAndServe(addr, path string) error {
+	if path == "" {
+		path = "/metrics"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, Handler())
+
+	return http.ListenAndServe(addr, mux)
+}
+
+// Handler returns a http.Handler that exposes the metrics in the prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		w := bufio.NewWriter(res)
+		defer w.Flush()
+
+		Write(w)
+	})
+}
+
+// Write writes all the metrics in the prometheus text format to the specified writer
+func Write(w *bufio.Writer) {
+	snapshot := stats.Take()
+
+	writeHeader(w, "redix_uptime_seconds", "gauge", "Number of seconds since the server started.")
+	fmt.Fprintf(w, "redix_uptime_seconds %d\n", int64(time.Since(snapshot.StartedAt).Seconds()))
+
+	writeHeader(w, "redix_connected_clients", "gauge", "Number of the currently connected clients.")
+	fmt.Fprintf(w, "redix_connected_clients %d\n", Connections.Value())
+
+	writeHeader(w, "redix_connections_received_total", "counter", "Total number of the accepted connections.")
+	fmt.Fprintf(w, "redix_connections_received_total %d\n", snapshot.ConnectionsReceived)
+
+	writeHeader(w, "redix_commands_processed_total", "counter", "Total number of the processed commands.")
+	fmt.Fprintf(w, "redix_commands_processed_total %d\n", snapshot.CommandsProcessed)
+
+	writeHeader(w, "redix_error_replies_total", "counter", "Total number of the error replies sent to the clients.")
+	fmt.Fprintf(w, "redix_error_replies_total %d\n", snapshot.ErrorReplies)
+
+	writeHeader(w, "redix_keyspace_hits_total", "counter", "Total number of the successful key lookups.")
+	fmt.Fprintf(w, "redix_keyspace_hits_total %d\n", snapshot.KeyspaceHits)
+
+	writeHeader(w, "redix_keyspace_misses_total", "counter", "Total number of the failed key lookups.")
+	fmt.Fprintf(w, "redix_keyspace_misses_total %d\n", snapshot.KeyspaceMisses)
+
+	writeHeader(w, "redix_pubsub_channels", "gauge", "Number of the channels having active subscriptions.")
+	fmt.Fprintf(w, "redix_pubsub_channels %d\n", snapshot.PubSubChannels)
+
+	writeHeader(w, "redix_command_calls_total", "counter", "Total number of calls per command.")
+	for _, cmd := range snapshot.Commands {
+		fmt.Fprintf(w, "redix_command_calls_total{command=%s} %d\n", quote(cmd.Name), cmd.Calls)
+	}
+
+	writeHeader(w, "redix_command_failed_calls_total", "counter", "Total number of failed calls per command.")
+	for _, cmd := range snapshot.Commands {
+		fmt.Fprintf(w, "redix_command_failed_calls_total{command=%s} %d\n", quote(cmd.Name), cmd.FailedCalls)
+	}
+
+	writeHistogramVec(w, "redix_command_duration_seconds", "command", "Commands execution latency.", CommandDuration)
+	writeHistogramVec(w, "redix_engine_duration_seconds", "operation", "Storage engine operations latency.", EngineDuration)
+
+	writeHeader(w, "redix_engine_errors_total", "counter", "Total number of failed storage engine operations.")
+	EngineErrors.each(func(label string, value int64) {
+		fmt.Fprintf(w, "redix_engine_errors_total{operation=%s} %d\n", quote(label), value)
+	})
+
+	writeHeader(w, "redix_writebehind_queue_depth", "gauge", "Number of the pending writes in the write-behind queue.")
+	fmt.Fprintf(w, "redix_writebehind_queue_depth %d\n", WriteBehindDepth.Value())
+
+	writeHeader(w, "redix_writebehind_writes_total", "counter", "Total number of the writes applied by the write-behind queue.")
+	fmt.Fprintf(w, "redix_writebehind_writes_total %d\n", WriteBehindWrites.Value())
+
+	writeHeader(w, "redix_writebehind_batches_total", "counter", "Total number of the batches applied by the write-behind queue.")
+	fmt.Fprintf(w, "redix_writebehind_batches_total %d\n", WriteBehindBatches.Value())
+
+	writeHeader(w, "redix_writebehind_failures_total", "counter", "Total number of the writes dropped by the write-behind queue.")
+	fmt.Fprintf(w, "redix_writebehind_failures_total %d\n", WriteBehindFailures.Value())
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeHistogramVec writes the specified histograms
+func writeHistogramVec(w *bufio.Writer, name, labelName, help string, vec *HistogramVec) {
+	writeHeader(w, name, "histogram", help)
+
+	vec.each(func(label string, h *histogram) {
+		label = labelName + "=" + quote(label)
+
+		for i, bound := range vec.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, label, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, label, h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, label, h.count)
+	})
+}
+
+// quote quotes the specified label value
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
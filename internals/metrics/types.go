@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets the default latency buckets (in seconds)
+var DefaultBuckets = []float64{0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Gauge represents a value that can go up and down
+type Gauge struct {
+	value int64
+}
+
+// Inc increments the gauge by 1
+func (g *Gauge) Inc() {
+	atomic.AddInt64(&g.value, 1)
+}
+
+// Dec decrements the gauge by 1
+func (g *Gauge) Dec() {
+	atomic.AddInt64(&g.value, -1)
+}
+
+// Value returns the current value of the gauge
+func (g *Gauge) Value() int64 {
+	return atomic.LoadInt64(&g.value)
+}
+
+// CounterVec represents a set of counters partitioned by a label value
+type CounterVec struct {
+	values map[string]int64
+	sync.Mutex
+}
+
+// NewCounterVec creates a new CounterVec
+func NewCounterVec() *CounterVec {
+	return &CounterVec{
+		values: map[string]int64{},
+	}
+}
+
+// Inc increments the counter of the specified label value by 1
+func (v *CounterVec) Inc(label string) {
+	v.Lock()
+	v.values[label]++
+	v.Unlock()
+}
+
+// each calls fn for each label value in order
+func (v *CounterVec) each(fn func(label string, value int64)) {
+	v.Lock()
+	defer v.Unlock()
+
+	for _, label := range sortedKeys(v.values) {
+		fn(label, v.values[label])
+	}
+}
+
+// histogram represents a cumulative histogram
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec represents a set of histograms partitioned by a label value
+type HistogramVec struct {
+	buckets    []float64
+	histograms map[string]*histogram
+	sync.Mutex
+}
+
+// NewHistogramVec creates a new HistogramVec using the specified buckets upper bounds
+func NewHistogramVec(buckets []float64) *HistogramVec {
+	return &HistogramVec{
+		buckets:    buckets,
+		histograms: map[string]*histogram{},
+	}
+}
+
+// Observe adds the specified value to the histogram of the specified label value
+func (v *HistogramVec) Observe(label string, value float64) {
+	v.Lock()
+	defer v.Unlock()
+
+	h, exists := v.histograms[label]
+	if !exists {
+		h = &histogram{counts: make([]uint64, len(v.buckets))}
+		v.histograms[label] = h
+	}
+
+	for i, bound := range v.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += value
+	h.count++
+}
+
+// each calls fn for each label value in order
+func (v *HistogramVec) each(fn func(label string, h *histogram)) {
+	v.Lock()
+	defer v.Unlock()
+
+	for _, label := range sortedKeys(v.histograms) {
+		fn(label, v.histograms[label])
+	}
+}
+
+// sortedKeys returns the keys of the specified map sorted
+func sortedKeys(m interface{}) []string {
+	var keys []string
+
+	switch m := m.(type) {
+	case map[string]int64:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*histogram:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Counter represents a value that can only go up
+type Counter struct {
+	value int64
+}
+
+// Inc increments the counter by 1
+func (c *Counter) Inc() {
+	atomic.AddInt64(&c.value, 1)
+}
+
+// Add increments the counter by the specified delta
+func (c *Counter) Add(delta int64) {
+	atomic.AddInt64(&c.value, delta)
+}
+
+// Value returns the current value of the counter
+func (c *Counter) Value() int64 {
+	return atomic.LoadInt64(&c.value)
+}
@@ -0,0 +1,207 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// errOutOfBounds the error of the memory ranges the module passed that are out of its memory
+var errOutOfBounds = errors.New("out of bounds memory access")
+
+// hostFunc represents a function of the host module
+type hostFunc struct {
+	params  []api.ValueType
+	results []api.ValueType
+	fn      func(ctx context.Context, mod api.Module, stack []uint64) error
+}
+
+// exportHost instantiates the host module of the functions the plugin module may import
+func (p *Plugin) exportHost(ctx context.Context) error {
+	funcs := map[string]hostFunc{
+		"reply_string": p.replyFunc(func(host Host, data []byte) { host.WriteString(string(data)) }),
+		"reply_error":  p.replyFunc(func(host Host, data []byte) { host.WriteError(string(data)) }),
+		"reply_bulk":   p.replyFunc(func(host Host, data []byte) { host.WriteBulk(data) }),
+		"reply_int": {
+			params: []api.ValueType{i64},
+			fn: func(_ context.Context, _ api.Module, stack []uint64) error {
+				p.host.WriteInt64(int64(stack[0]))
+				return nil
+			},
+		},
+		"reply_null": {
+			fn: func(context.Context, api.Module, []uint64) error {
+				p.host.WriteNull()
+				return nil
+			},
+		},
+		"reply_array": {
+			params: []api.ValueType{i32},
+			fn: func(_ context.Context, _ api.Module, stack []uint64) error {
+				p.host.WriteArray(int(api.DecodeI32(stack[0])))
+				return nil
+			},
+		},
+		"read":    {params: []api.ValueType{i32, i32, i32, i32}, results: []api.ValueType{i32}, fn: p.read},
+		"write":   {params: []api.ValueType{i32, i32, i32, i32, i64}, fn: p.write},
+		"delete":  {params: []api.ValueType{i32, i32}, results: []api.ValueType{i32}, fn: p.delete},
+		"iterate": {params: []api.ValueType{i32, i32}, results: []api.ValueType{i32}, fn: p.iterate},
+	}
+
+	builder := p.runtime.NewHostModuleBuilder(hostModuleName)
+
+	for name, f := range funcs {
+		f := f
+
+		builder.NewFunctionBuilder().WithGoModuleFunction(api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			if err := f.fn(ctx, mod, stack); err != nil {
+				// the error aborts the command, it is kept to be reported as is
+				p.err = err
+				panic(err)
+			}
+		}), f.params, f.results).Export(name)
+	}
+
+	_, err := builder.Instantiate(ctx)
+
+	return err
+}
+
+// replyFunc creates a host function of (ptr i32, len i32) passing the specified memory range to fn
+func (p *Plugin) replyFunc(fn func(host Host, data []byte)) hostFunc {
+	return hostFunc{
+		params: []api.ValueType{i32, i32},
+		fn: func(_ context.Context, mod api.Module, stack []uint64) error {
+			data, err := read(mod, stack[0], stack[1])
+			if err != nil {
+				return err
+			}
+
+			fn(p.host, data)
+
+			return nil
+		},
+	}
+}
+
+// read implements read(key i32, key_len i32, buf i32, cap i32) -> i32
+func (p *Plugin) read(_ context.Context, mod api.Module, stack []uint64) error {
+	key, err := read(mod, stack[0], stack[1])
+	if err != nil {
+		return err
+	}
+
+	value, exists, err := p.host.Read(key)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		stack[0] = api.EncodeI32(-1)
+		return nil
+	}
+
+	// the value is only copied if it fits, so the module may retry using a larger buffer
+	if len(value) <= int(api.DecodeU32(stack[3])) && !mod.Memory().Write(api.DecodeU32(stack[2]), value) {
+		return errOutOfBounds
+	}
+
+	stack[0] = api.EncodeU32(uint32(len(value)))
+
+	return nil
+}
+
+// write implements write(key i32, key_len i32, val i32, val_len i32, ttl_ms i64)
+func (p *Plugin) write(_ context.Context, mod api.Module, stack []uint64) error {
+	key, err := read(mod, stack[0], stack[1])
+	if err != nil {
+		return err
+	}
+
+	value, err := read(mod, stack[2], stack[3])
+	if err != nil {
+		return err
+	}
+
+	ttl := int64(stack[4])
+	if ttl < 0 {
+		return fmt.Errorf("invalid ttl (%d)", ttl)
+	}
+
+	return p.host.Write(key, value, time.Duration(ttl)*time.Millisecond)
+}
+
+// delete implements delete(key i32, key_len i32) -> i32
+func (p *Plugin) delete(_ context.Context, mod api.Module, stack []uint64) error {
+	key, err := read(mod, stack[0], stack[1])
+	if err != nil {
+		return err
+	}
+
+	existed, err := p.host.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	stack[0] = 0
+	if existed {
+		stack[0] = 1
+	}
+
+	return nil
+}
+
+// iterate implements iterate(prefix i32, prefix_len i32) -> i32
+func (p *Plugin) iterate(ctx context.Context, mod api.Module, stack []uint64) error {
+	prefix, err := read(mod, stack[0], stack[1])
+	if err != nil {
+		return err
+	}
+
+	callback := mod.ExportedFunction(iterateCallback)
+	if callback == nil {
+		return fmt.Errorf("the module must export the function %s(i32, i32, i32, i32) -> i32", iterateCallback)
+	}
+
+	var visited uint32
+
+	err = p.host.Iterate(prefix, func(key, value []byte) (bool, error) {
+		visited++
+
+		ptr, err := p.alloc(ctx, len(key)+len(value))
+		if err != nil {
+			return false, err
+		}
+
+		mod.Memory().Write(ptr, key)
+		mod.Memory().Write(ptr+uint32(len(key)), value)
+
+		results, err := callback.Call(ctx, uint64(ptr), uint64(len(key)), uint64(ptr+uint32(len(key))), uint64(len(value)))
+		if err != nil {
+			return false, err
+		}
+
+		return api.DecodeU32(results[0]) == 0, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	stack[0] = api.EncodeU32(visited)
+
+	return nil
+}
+
+// read copies the memory range located by the specified ptr and len arguments
+func read(mod api.Module, ptr, size uint64) ([]byte, error) {
+	data, ok := mod.Memory().Read(api.DecodeU32(ptr), api.DecodeU32(size))
+	if !ok {
+		return nil, errOutOfBounds
+	}
+
+	// the returned slice is a view of the module memory
+	return append([]byte(nil), data...), nil
+}
@@ -0,0 +1,314 @@
+// Package plugins loads the custom commands implemented in WebAssembly modules.
+//
+// A plugin module exports its commands as functions named command_<name> taking (argv i32, argc i32),
+// argv points to argc pairs of (ptr i32, len i32) little endian integers, each pair locates an argument.
+// The module must also export alloc(size i32) -> i32, the host uses it to allocate the memory it passes
+// to the module (the arguments and the iterated entries), the module owns (and may reuse) that memory.
+//
+// The module may import the following functions from the "redix" module:
+//
+//	reply_string(ptr i32, len i32)     replies with a status
+//	reply_error(ptr i32, len i32)      replies with an error
+//	reply_bulk(ptr i32, len i32)       replies with a bulk string
+//	reply_int(n i64)                   replies with an integer
+//	reply_null()                       replies with a null
+//	reply_array(n i32)                 replies with an array, the next n replies are its items
+//	read(key i32, key_len i32, buf i32, cap i32) -> i32
+//	                                   copies the value of the key into buf if it fits in cap,
+//	                                   returns its length or -1 if the key doesn't exist
+//	write(key i32, key_len i32, val i32, val_len i32, ttl_ms i64)
+//	                                   sets the value of the key, a ttl of 0 means it never expires
+//	delete(key i32, key_len i32) -> i32
+//	                                   deletes the key, returns 1 if it existed otherwise 0
+//	iterate(prefix i32, prefix_len i32) -> i32
+//	                                   calls the exported iterate_callback(key i32, key_len i32, val i32, val_len i32) -> i32
+//	                                   for each key having the prefix until it returns non-zero, returns the visited keys count
+//
+// The keys are relative to the namespace (db) of the calling client.
+//
+// The modules are validated and compiled by wazero, a module whose command runs longer than the time limit
+// is aborted then instantiated again, so the state it kept in its memory is lost.
+package plugins
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// the exported functions names
+const (
+	commandPrefix    = "command_"
+	allocFunc        = "alloc"
+	iterateCallback  = "iterate_callback"
+	hostModuleName   = "redix"
+	argDescriptorLen = 8
+)
+
+const (
+	i32 = api.ValueTypeI32
+	i64 = api.ValueTypeI64
+)
+
+var (
+	commandType  = signature{params: []api.ValueType{i32, i32}}
+	allocType    = signature{params: []api.ValueType{i32}, results: []api.ValueType{i32}}
+	callbackType = signature{params: []api.ValueType{i32, i32, i32, i32}, results: []api.ValueType{i32}}
+)
+
+// Host represents the caller of a plugin command, the plugin replies and accesses the data through it
+type Host interface {
+	// Read returns the value of the specified key and whether it exists or not
+	Read(key []byte) ([]byte, bool, error)
+
+	// Write sets the value of the specified key, a zero ttl means that it never expires
+	Write(key, value []byte, ttl time.Duration) error
+
+	// Delete deletes the specified key and reports whether it existed or not
+	Delete(key []byte) (bool, error)
+
+	// Iterate calls fn for each key having the specified prefix until it returns false
+	Iterate(prefix []byte, fn func(key, value []byte) (bool, error)) error
+
+	WriteString(str string)
+	WriteError(msg string)
+	WriteBulk(bulk []byte)
+	WriteInt64(num int64)
+	WriteNull()
+	WriteArray(count int)
+}
+
+// Plugin represents a loaded plugin module, its commands are executed one at a time
+type Plugin struct {
+	// Name the name of the plugin
+	Name string
+
+	// Commands the names of the provided commands
+	Commands []string
+
+	runtime   wazero.Runtime
+	compiled  wazero.CompiledModule
+	module    api.Module
+	exports   map[string]string
+	timeLimit time.Duration
+
+	// the state of the running command, err is the error a host function failed with
+	host Host
+	err  error
+
+	sync.Mutex
+}
+
+// signature represents the params and the results types of a function
+type signature struct {
+	params  []api.ValueType
+	results []api.ValueType
+}
+
+// matches whether the specified function has the signature
+func (s signature) matches(def api.FunctionDefinition) bool {
+	return string(def.ParamTypes()) == string(s.params) && string(def.ResultTypes()) == string(s.results)
+}
+
+// Open loads the plugin module from the specified file,
+// the commands calls are aborted once they run longer than the specified time limit (0 means never).
+func Open(name, filename string, timeLimit time.Duration) (*Plugin, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	p := &Plugin{
+		Name:      name,
+		exports:   map[string]string{},
+		timeLimit: timeLimit,
+		runtime:   wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true)),
+	}
+
+	if err := p.load(ctx, data); err != nil {
+		p.runtime.Close(ctx)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// load compiles (which validates) the specified module then instantiates it
+func (p *Plugin) load(ctx context.Context, data []byte) error {
+	if err := p.exportHost(ctx); err != nil {
+		return err
+	}
+
+	var err error
+
+	p.compiled, err = p.runtime.CompileModule(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	exported := p.compiled.ExportedFunctions()
+
+	for exportName, def := range exported {
+		if !strings.HasPrefix(exportName, commandPrefix) {
+			continue
+		}
+
+		command := strings.ToLower(strings.TrimPrefix(exportName, commandPrefix))
+		if command == "" {
+			return fmt.Errorf("the export (%s) doesn't name a command", exportName)
+		}
+
+		if !commandType.matches(def) {
+			return fmt.Errorf("the command (%s) must be a function of (i32, i32)", command)
+		}
+
+		if _, exists := p.exports[command]; exists {
+			return fmt.Errorf("the command (%s) is exported more than once", command)
+		}
+
+		p.exports[command] = exportName
+		p.Commands = append(p.Commands, command)
+	}
+
+	if len(p.Commands) < 1 {
+		return fmt.Errorf("the module doesn't export any %s<name> function", commandPrefix)
+	}
+
+	sort.Strings(p.Commands)
+
+	if def, ok := exported[allocFunc]; !ok || !allocType.matches(def) {
+		return fmt.Errorf("the module must export the function %s(i32) -> i32", allocFunc)
+	}
+
+	if def, ok := exported[iterateCallback]; ok && !callbackType.matches(def) {
+		return fmt.Errorf("the module must export the function %s(i32, i32, i32, i32) -> i32", iterateCallback)
+	}
+
+	return p.instantiate(ctx)
+}
+
+// instantiate instantiates the compiled module, replacing the current instance (if any)
+func (p *Plugin) instantiate(ctx context.Context) error {
+	if p.module != nil {
+		p.module.Close(ctx)
+		p.module = nil
+	}
+
+	module, err := p.runtime.InstantiateModule(ctx, p.compiled, wazero.NewModuleConfig().WithName(p.Name))
+	if err != nil {
+		return err
+	}
+
+	if module.Memory() == nil {
+		module.Close(ctx)
+		return fmt.Errorf("the module must export its memory")
+	}
+
+	p.module = module
+
+	return nil
+}
+
+// Call executes the specified command using the specified arguments, the replies are written to the host,
+// an error is returned if the command couldn't complete, the replies written so far must be discarded then.
+func (p *Plugin) Call(command string, args [][]byte, host Host) error {
+	if _, exists := p.exports[command]; !exists {
+		return fmt.Errorf("unknown command (%s)", command)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// the module has been closed by an aborted command
+	if p.module == nil {
+		if err := p.instantiate(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	if p.timeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeLimit)
+		defer cancel()
+	}
+
+	p.host, p.err = host, nil
+	defer func() { p.host, p.err = nil, nil }()
+
+	err := p.call(ctx, command, args)
+
+	// the host functions errors are reported as is, while the traps are reported without their stack trace
+	if p.err != nil {
+		err = p.err
+	} else if err != nil {
+		err = errors.New(strings.SplitN(err.Error(), "\n", 2)[0])
+	}
+
+	if ctx.Err() != nil {
+		// the aborted instance is closed, the next command instantiates it again
+		p.module.Close(context.Background())
+		p.module = nil
+
+		return fmt.Errorf("the command has been interrupted as it exceeded the time limit")
+	}
+
+	return err
+}
+
+// call passes the specified arguments to the specified command then calls it
+func (p *Plugin) call(ctx context.Context, command string, args [][]byte) error {
+	size := len(args) * argDescriptorLen
+	for _, arg := range args {
+		size += len(arg)
+	}
+
+	ptr, err := p.alloc(ctx, size)
+	if err != nil {
+		return err
+	}
+
+	// the arguments descriptors come first followed by the arguments
+	buf := make([]byte, size)
+	offset := len(args) * argDescriptorLen
+
+	for i, arg := range args {
+		binary.LittleEndian.PutUint32(buf[i*argDescriptorLen:], ptr+uint32(offset))
+		binary.LittleEndian.PutUint32(buf[i*argDescriptorLen+4:], uint32(len(arg)))
+		offset += copy(buf[offset:], arg)
+	}
+
+	if !p.module.Memory().Write(ptr, buf) {
+		return errOutOfBounds
+	}
+
+	_, err = p.module.ExportedFunction(p.exports[command]).Call(ctx, uint64(ptr), uint64(len(args)))
+
+	return err
+}
+
+// alloc allocates the specified size in the module memory
+func (p *Plugin) alloc(ctx context.Context, size int) (uint32, error) {
+	results, err := p.module.ExportedFunction(allocFunc).Call(ctx, uint64(size))
+	if err != nil {
+		return 0, err
+	}
+
+	ptr := api.DecodeU32(results[0])
+	if uint64(ptr)+uint64(size) > uint64(p.module.Memory().Size()) {
+		return 0, fmt.Errorf("%s returned an out of bounds memory", allocFunc)
+	}
+
+	return ptr, nil
+}
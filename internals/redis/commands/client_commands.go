@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	// CLIENT <subcommand> [<arg> ...]
+	Register(&Command{
+		Name:       "client",
+		Arity:      -2,
+		Flags:      []string{FlagNoScript, FlagLoading, FlagStale},
+		Categories: []string{"@slow", "@connection"},
+		Group:      "connection",
+		Summary:    "Inspects and manages the client connections",
+		Arguments: []*Argument{
+			{Name: "subcommand", Type: ArgString},
+			{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+		},
+		Handler: func(c *Context) {
+			subcommand := strings.ToLower(string(c.Argv[0]))
+
+			switch subcommand {
+			case "id":
+				c.Conn.WriteInt64(c.Client().ID)
+			case "info":
+				c.Conn.WriteBulkString(c.Client().String() + "\n")
+			case "list":
+				clientList(c)
+			case "getname":
+				if name := c.Client().Name(); name != "" {
+					c.Conn.WriteBulkString(name)
+				} else {
+					c.Writer().WriteNull()
+				}
+			case "setname":
+				if c.Argc != 2 {
+					c.Conn.WriteError("ERR wrong number of arguments for 'client|setname' command")
+					return
+				}
+
+				if bytes.ContainsAny(c.Argv[1], " \n") {
+					c.Conn.WriteError("ERR Client names cannot contain spaces, newlines or special characters.")
+					return
+				}
+
+				c.Client().SetName(string(c.Argv[1]))
+				c.Conn.WriteString("OK")
+			case "kill":
+				clientKill(c)
+			case "pause":
+				clientPause(c)
+			case "unpause":
+				UnpauseClients()
+				c.Conn.WriteString("OK")
+			default:
+				c.Conn.WriteError("ERR unknown subcommand '" + subcommand + "'")
+			}
+		},
+	})
+}
+
+// CLIENT LIST [ID client-id [client-id ...]]
+func clientList(c *Context) {
+	var clients []*Client
+
+	if c.Argc > 1 {
+		if c.Argc < 3 || strings.ToLower(string(c.Argv[1])) != "id" {
+			c.Conn.WriteError("ERR syntax error")
+			return
+		}
+
+		for _, arg := range c.Argv[2:] {
+			id, err := strconv.ParseInt(string(arg), 10, 64)
+			if err != nil {
+				c.Conn.WriteError("ERR Invalid client ID")
+				return
+			}
+
+			if client, exists := FindClient(id); exists {
+				clients = append(clients, client)
+			}
+		}
+	} else {
+		clients = Clients()
+	}
+
+	var buf strings.Builder
+
+	for _, client := range clients {
+		buf.WriteString(client.String())
+		buf.WriteString("\n")
+	}
+
+	c.Conn.WriteBulkString(buf.String())
+}
+
+// CLIENT KILL <ip:port>
+// CLIENT KILL [ID client-id] [ADDR ip:port] [SKIPME yes/no]
+func clientKill(c *Context) {
+	if c.Argc == 2 {
+		addr := string(c.Argv[1])
+
+		for _, client := range Clients() {
+			if client.Addr == addr {
+				client.Kill()
+				c.Conn.WriteString("OK")
+				return
+			}
+		}
+
+		c.Conn.WriteError("ERR No such client")
+		return
+	}
+
+	if c.Argc < 2 || c.Argc%2 == 0 {
+		c.Conn.WriteError("ERR syntax error")
+		return
+	}
+
+	var id int64
+	var addr string
+	skipme := true
+
+	for i := 1; i < c.Argc; i += 2 {
+		val := string(c.Argv[i+1])
+
+		switch strings.ToLower(string(c.Argv[i])) {
+		case "id":
+			n, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				c.Conn.WriteError("ERR client-id should be greater than 0")
+				return
+			}
+			id = n
+		case "addr":
+			addr = val
+		case "skipme":
+			switch strings.ToLower(val) {
+			case "yes":
+				skipme = true
+			case "no":
+				skipme = false
+			default:
+				c.Conn.WriteError("ERR syntax error")
+				return
+			}
+		default:
+			c.Conn.WriteError("ERR syntax error")
+			return
+		}
+	}
+
+	killed := 0
+	me := c.Client()
+
+	for _, client := range Clients() {
+		if (id != 0 && client.ID != id) || (addr != "" && client.Addr != addr) {
+			continue
+		}
+
+		if skipme && client == me {
+			continue
+		}
+
+		client.Kill()
+		killed++
+	}
+
+	c.Conn.WriteInt(killed)
+}
+
+// CLIENT PAUSE <timeout> [WRITE | ALL]
+func clientPause(c *Context) {
+	if c.Argc < 2 || c.Argc > 3 {
+		c.Conn.WriteError("ERR wrong number of arguments for 'client|pause' command")
+		return
+	}
+
+	timeout, err := strconv.ParseInt(string(c.Argv[1]), 10, 64)
+	if err != nil || timeout < 0 {
+		c.Conn.WriteError("ERR timeout is not an integer or out of range")
+		return
+	}
+
+	writesOnly := false
+
+	if c.Argc == 3 {
+		switch strings.ToLower(string(c.Argv[2])) {
+		case "all":
+		case "write":
+			writesOnly = true
+		default:
+			c.Conn.WriteError("ERR syntax error")
+			return
+		}
+	}
+
+	PauseClients(time.Duration(timeout)*time.Millisecond, writesOnly)
+
+	c.Conn.WriteString("OK")
+}
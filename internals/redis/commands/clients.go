@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tidwall/redcon"
+)
+
+// Client represents a connected client
+type Client struct {
+	ID        int64
+	Addr      string
+	CreatedAt time.Time
+	Conn      redcon.Conn
+
+	name          string
+	db            int
+	lastCmd       string
+	lastActiveAt  time.Time
+	subscriptions int
+
+	sync.RWMutex
+}
+
+var (
+	clientsMap     = map[int64]*Client{}
+	clientsMapLock = &sync.RWMutex{}
+	clientIDSeq    int64
+	pausedUntil    int64
+
+	// pausedWritesOnly whether the current pause (if any) is in the WRITE mode (1) or the ALL mode (0)
+	pausedWritesOnly int32
+)
+
+// RegisterClient creates a new client for the specified conn and adds it to the registry
+func RegisterClient(conn redcon.Conn) *Client {
+	now := time.Now()
+
+	client := &Client{
+		ID:           atomic.AddInt64(&clientIDSeq, 1),
+		Addr:         conn.RemoteAddr(),
+		CreatedAt:    now,
+		Conn:         conn,
+		lastActiveAt: now,
+	}
+
+	clientsMapLock.Lock()
+	clientsMap[client.ID] = client
+	clientsMapLock.Unlock()
+
+	return client
+}
+
+// UnregisterClient removes the specified client from the registry
+func UnregisterClient(client *Client) {
+	clientsMapLock.Lock()
+	delete(clientsMap, client.ID)
+	clientsMapLock.Unlock()
+}
+
+// FindClient fetches the client with the specified id if exists
+func FindClient(id int64) (*Client, bool) {
+	clientsMapLock.RLock()
+	client, exists := clientsMap[id]
+	clientsMapLock.RUnlock()
+
+	return client, exists
+}
+
+// Clients returns all the registered clients ordered by their id
+func Clients() []*Client {
+	clientsMapLock.RLock()
+
+	clients := make([]*Client, 0, len(clientsMap))
+	for _, client := range clientsMap {
+		clients = append(clients, client)
+	}
+
+	clientsMapLock.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ID < clients[j].ID
+	})
+
+	return clients
+}
+
+// PauseClients suspends processing the clients commands for the specified duration,
+// only the commands modifying the data are suspended if writesOnly is true.
+func PauseClients(d time.Duration, writesOnly bool) {
+	mode := int32(0)
+	if writesOnly {
+		mode = 1
+	}
+
+	atomic.StoreInt32(&pausedWritesOnly, mode)
+	atomic.StoreInt64(&pausedUntil, time.Now().Add(d).UnixNano())
+}
+
+// UnpauseClients resumes processing the clients commands
+func UnpauseClients() {
+	atomic.StoreInt64(&pausedUntil, 0)
+}
+
+// WaitIfPaused blocks till the clients pause (if any) ends, the write commands
+// are blocked by both of the pause modes while the others are only blocked by the ALL mode.
+func WaitIfPaused(write bool) {
+	for {
+		remaining := time.Until(time.Unix(0, atomic.LoadInt64(&pausedUntil)))
+		if remaining <= 0 {
+			return
+		}
+
+		if !write && atomic.LoadInt32(&pausedWritesOnly) == 1 {
+			return
+		}
+
+		if remaining > 10*time.Millisecond {
+			remaining = 10 * time.Millisecond
+		}
+
+		time.Sleep(remaining)
+	}
+}
+
+// Name returns the client name
+func (c *Client) Name() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.name
+}
+
+// SetName sets the client name
+func (c *Client) SetName(name string) {
+	c.Lock()
+	c.name = name
+	c.Unlock()
+}
+
+// SetDB sets the currently selected db index
+func (c *Client) SetDB(db int) {
+	c.Lock()
+	c.db = db
+	c.Unlock()
+}
+
+// Touch marks the client as active and records the command it is executing
+func (c *Client) Touch(cmd string) {
+	c.Lock()
+	c.lastCmd = cmd
+	c.lastActiveAt = time.Now()
+	c.Unlock()
+}
+
+// AddSubscriptions increments/decrements the client subscriptions count by the specified delta
+func (c *Client) AddSubscriptions(delta int) {
+	c.Lock()
+	c.subscriptions += delta
+	c.Unlock()
+}
+
+// Subscriptions returns the number of active subscriptions of the client
+func (c *Client) Subscriptions() int {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.subscriptions
+}
+
+// scriptClient returns an unregistered copy of the client for the scripts it runs,
+// so the commands they call (i.e SELECT) don't change the client itself.
+func (c *Client) scriptClient() *Client {
+	c.RLock()
+	defer c.RUnlock()
+
+	return &Client{
+		ID:           c.ID,
+		Addr:         "lua",
+		CreatedAt:    time.Now(),
+		name:         c.name,
+		db:           c.db,
+		lastActiveAt: time.Now(),
+	}
+}
+
+// Kill closes the underlying network connection of the client
+func (c *Client) Kill() error {
+	return c.Conn.NetConn().Close()
+}
+
+// String returns the client info line as in CLIENT LIST
+func (c *Client) String() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := time.Now()
+
+	return fmt.Sprintf(
+		"id=%d addr=%s name=%s age=%d idle=%d db=%d sub=%d cmd=%s",
+		c.ID,
+		c.Addr,
+		c.name,
+		int64(now.Sub(c.CreatedAt).Seconds()),
+		int64(now.Sub(c.lastActiveAt).Seconds()),
+		c.db,
+		c.subscriptions,
+		c.lastCmd,
+	)
+}
@@ -0,0 +1,215 @@
+package commands
+
+import "strings"
+
+func init() {
+	// COMMAND [COUNT | LIST | INFO [<command> ...] | DOCS [<command> ...] | GETKEYS <command> [<arg> ...]]
+	Register(&Command{
+		Name:       "command",
+		Arity:      -1,
+		Flags:      []string{FlagLoading, FlagStale},
+		Categories: []string{"@slow", "@connection"},
+		Group:      "server",
+		Summary:    "Returns the details about the commands",
+		Arguments: []*Argument{
+			{Name: "subcommand", Type: ArgString, Optional: true},
+			{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+		},
+		Handler: func(c *Context) {
+			if c.Argc < 1 {
+				writeCommandsInfo(c.Writer(), Commands())
+				return
+			}
+
+			subcommand := strings.ToLower(string(c.Argv[0]))
+
+			switch subcommand {
+			case "count":
+				c.Conn.WriteInt(len(Commands()))
+			case "list":
+				all := Commands()
+
+				c.Conn.WriteArray(len(all))
+				for _, cmd := range all {
+					c.Conn.WriteBulkString(cmd.Name)
+				}
+			case "info":
+				writeCommandsInfo(c.Writer(), lookupCommands(c.Argv[1:]))
+			case "docs":
+				commandDocs(c)
+			case "getkeys":
+				commandGetKeys(c)
+			default:
+				c.Conn.WriteError("ERR unknown subcommand '" + subcommand + "'")
+			}
+		},
+	})
+}
+
+// lookupCommands fetches the specified commands, the unknown ones are nil,
+// all the commands are returned if none is specified.
+func lookupCommands(names [][]byte) []*Command {
+	if len(names) < 1 {
+		return Commands()
+	}
+
+	result := make([]*Command, len(names))
+
+	for i, name := range names {
+		result[i], _ = Lookup(string(name))
+	}
+
+	return result
+}
+
+// writeCommandsInfo writes the specified commands info, the nil commands are written as nulls
+func writeCommandsInfo(w *Writer, cmds []*Command) {
+	w.WriteArray(len(cmds))
+
+	for _, cmd := range cmds {
+		if cmd == nil {
+			w.WriteNull()
+			continue
+		}
+
+		w.WriteArray(10)
+		w.WriteBulkString(cmd.Name)
+		w.WriteInt(cmd.Arity)
+
+		w.WriteSet(len(cmd.Flags))
+		for _, flag := range cmd.Flags {
+			w.WriteString(flag)
+		}
+
+		w.WriteInt(cmd.FirstKey)
+		w.WriteInt(cmd.LastKey)
+		w.WriteInt(cmd.KeyStep)
+
+		w.WriteSet(len(cmd.Categories))
+		for _, category := range cmd.Categories {
+			w.WriteString(category)
+		}
+
+		// the tips, the key specs and the subcommands aren't supported
+		w.WriteSet(0)
+		w.WriteArray(0)
+		w.WriteArray(0)
+	}
+}
+
+// COMMAND DOCS [<command> ...]
+func commandDocs(c *Context) {
+	var cmds []*Command
+
+	for _, cmd := range lookupCommands(c.Argv[1:]) {
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	w := c.Writer()
+
+	w.WriteMap(len(cmds))
+
+	for _, cmd := range cmds {
+		w.WriteBulkString(cmd.Name)
+
+		fields := 2
+		if len(cmd.Arguments) > 0 {
+			fields++
+		}
+
+		w.WriteMap(fields)
+		w.WriteBulkString("summary")
+		w.WriteBulkString(cmd.Summary)
+		w.WriteBulkString("group")
+		w.WriteBulkString(cmd.Group)
+
+		if len(cmd.Arguments) > 0 {
+			w.WriteBulkString("arguments")
+			writeArguments(w, cmd.Arguments)
+		}
+	}
+}
+
+// writeArguments writes the docs of the specified arguments
+func writeArguments(w *Writer, args []*Argument) {
+	w.WriteArray(len(args))
+
+	for _, arg := range args {
+		var flags []string
+		if arg.Optional {
+			flags = append(flags, "optional")
+		}
+		if arg.Multiple {
+			flags = append(flags, "multiple")
+		}
+
+		fields := 2
+		if arg.Token != "" {
+			fields++
+		}
+		if len(flags) > 0 {
+			fields++
+		}
+		if len(arg.Arguments) > 0 {
+			fields++
+		}
+
+		w.WriteMap(fields)
+		w.WriteBulkString("name")
+		w.WriteBulkString(arg.Name)
+		w.WriteBulkString("type")
+		w.WriteBulkString(arg.Type)
+
+		if arg.Token != "" {
+			w.WriteBulkString("token")
+			w.WriteBulkString(arg.Token)
+		}
+
+		if len(flags) > 0 {
+			w.WriteBulkString("flags")
+			w.WriteArray(len(flags))
+			for _, flag := range flags {
+				w.WriteString(flag)
+			}
+		}
+
+		if len(arg.Arguments) > 0 {
+			w.WriteBulkString("arguments")
+			writeArguments(w, arg.Arguments)
+		}
+	}
+}
+
+// COMMAND GETKEYS <command> [<arg> ...]
+func commandGetKeys(c *Context) {
+	if c.Argc < 2 {
+		c.Conn.WriteError("ERR wrong number of arguments for 'command|getkeys' command")
+		return
+	}
+
+	cmd, exists := Lookup(string(c.Argv[1]))
+	if !exists {
+		c.Conn.WriteError("ERR Invalid command specified")
+		return
+	}
+
+	argv := c.Argv[2:]
+
+	if !cmd.AcceptsArgc(len(argv)) {
+		c.Conn.WriteError("ERR Invalid number of arguments specified for command")
+		return
+	}
+
+	keys := cmd.Keys(argv)
+	if len(keys) < 1 {
+		c.Conn.WriteError("ERR The command has no key arguments")
+		return
+	}
+
+	c.Conn.WriteArray(len(keys))
+	for _, key := range keys {
+		c.Conn.WriteBulk(key)
+	}
+}
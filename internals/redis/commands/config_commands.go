@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/alash3al/redix/internals/config"
+)
+
+func init() {
+	// CONFIG GET <pattern> [<pattern> ...] | SET <setting> <value> [<setting> <value> ...] | REWRITE
+	Register(&Command{
+		Name:       "config",
+		Arity:      -2,
+		Flags:      []string{FlagAdmin, FlagNoScript, FlagLoading, FlagStale},
+		Categories: []string{"@admin", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "Inspects and changes the server configurations",
+		Arguments: []*Argument{
+			{Name: "subcommand", Type: ArgString},
+			{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+		},
+		Handler: func(c *Context) {
+			subcommand := strings.ToLower(string(c.Argv[0]))
+
+			switch subcommand {
+			case "get":
+				configGet(c)
+			case "set":
+				configSet(c)
+			case "rewrite":
+				if err := config.Rewrite(config.Current()); err != nil {
+					c.Conn.WriteError("ERR Rewriting config file: " + err.Error())
+					return
+				}
+
+				c.Conn.WriteString("OK")
+			default:
+				c.Conn.WriteError("ERR unknown subcommand '" + subcommand + "'")
+			}
+		},
+	})
+}
+
+// CONFIG GET <pattern> [<pattern> ...]
+func configGet(c *Context) {
+	if c.Argc < 2 {
+		c.Conn.WriteError("ERR wrong number of arguments for 'config|get' command")
+		return
+	}
+
+	var matched []*config.Setting
+
+	for _, setting := range config.Settings() {
+		for _, pattern := range c.Argv[1:] {
+			if ok, _ := path.Match(strings.ToLower(string(pattern)), setting.Name); ok {
+				matched = append(matched, setting)
+				break
+			}
+		}
+	}
+
+	w := c.Writer()
+
+	w.WriteMap(len(matched))
+	for _, setting := range matched {
+		w.WriteBulkString(setting.Name)
+		w.WriteBulkString(setting.Get(c.Cfg))
+	}
+}
+
+// CONFIG SET <setting> <value> [<setting> <value> ...]
+// either all the specified settings are applied or none of them.
+func configSet(c *Context) {
+	if c.Argc < 3 || c.Argc%2 == 0 {
+		c.Conn.WriteError("ERR wrong number of arguments for 'config|set' command")
+		return
+	}
+
+	err := config.Update(func(cfg *config.Config) error {
+		for i := 1; i < c.Argc; i += 2 {
+			setting, exists := config.FindSetting(string(c.Argv[i]))
+			if !exists {
+				return fmt.Errorf("Unknown option or number of arguments for CONFIG SET - '%s'", c.Argv[i])
+			}
+
+			if err := setting.Set(cfg, string(c.Argv[i+1])); err != nil {
+				return fmt.Errorf("CONFIG SET failed - %s", err.Error())
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		c.Conn.WriteError("ERR " + err.Error())
+		return
+	}
+
+	c.Conn.WriteString("OK")
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/alash3al/redix/internals/config"
 	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/writebehind"
 	"github.com/tidwall/redcon"
 )
 
@@ -14,6 +15,7 @@ import (
 type Context struct {
 	Conn   redcon.Conn
 	Engine contract.Engine
+	Queue  *writebehind.Queue
 	Cfg    *config.Config
 	Argv   [][]byte
 	Argc   int
@@ -53,3 +55,24 @@ func (c *Context) AbsoluteKeyPath(k ...[]byte) []byte {
 	ns, _ := c.SessionGet("namespace")
 	return []byte(ns.(string) + strings.TrimLeft(string(bytes.Join(k, []byte("/"))), "/"))
 }
+
+// Protocol returns the RESP protocol version negotiated by the current connection
+func (c *Context) Protocol() int {
+	if proto, ok := c.SessionGet("protocol"); ok {
+		return proto.(int)
+	}
+
+	return RESP2
+}
+
+// Writer returns a protocol aware writer for the current connection
+func (c *Context) Writer() *Writer {
+	return NewWriter(c.Conn, c.Protocol())
+}
+
+// Client returns the client that owns the current connection
+func (c *Context) Client() *Client {
+	client, _ := c.SessionGet("client")
+
+	return client.(*Client)
+}
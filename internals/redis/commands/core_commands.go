@@ -3,354 +3,542 @@ package commands
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/scripting"
+	"github.com/alash3al/redix/internals/stats"
 )
 
 func init() {
 	// PING
-	HandleFunc("ping", func(c *Context) {
-		c.Conn.WriteString("PONG")
+	Register(&Command{
+		Name:       "ping",
+		Arity:      -1,
+		Flags:      []string{FlagFast, FlagStale},
+		Categories: []string{"@fast", "@connection"},
+		Group:      "connection",
+		Summary:    "Returns the server's liveliness response",
+		Arguments: []*Argument{
+			{Name: "message", Type: ArgString, Optional: true},
+		},
+		Handler: func(c *Context) {
+			c.Conn.WriteString("PONG")
+		},
 	})
 
 	// QUIT
-	HandleFunc("quit", func(c *Context) {
-		c.Conn.WriteString("OK")
-		c.Conn.Close()
+	Register(&Command{
+		Name:       "quit",
+		Arity:      -1,
+		Flags:      []string{FlagNoScript, FlagLoading, FlagStale, FlagFast},
+		Categories: []string{"@fast", "@connection"},
+		Group:      "connection",
+		Summary:    "Closes the connection",
+		Handler: func(c *Context) {
+			c.Conn.WriteString("OK")
+			c.Conn.Close()
+		},
 	})
 
 	// SELECT <DB index>
-	HandleFunc("select", func(c *Context) {
-		if c.Argc < 1 {
-			c.Conn.WriteError("Err invalid arguments supplied")
-			return
-		}
-
-		i, err := strconv.Atoi(string(c.Argv[0]))
-		if err != nil {
-			c.Conn.WriteError("Err invalid DB index")
-			return
-		}
+	Register(&Command{
+		Name:       "select",
+		Arity:      2,
+		Flags:      []string{FlagLoading, FlagStale, FlagFast},
+		Categories: []string{"@fast", "@connection"},
+		Group:      "connection",
+		Summary:    "Changes the selected database",
+		Arguments: []*Argument{
+			{Name: "index", Type: ArgInteger},
+		},
+		Handler: func(c *Context) {
+			i, err := strconv.Atoi(string(c.Argv[0]))
+			if err != nil {
+				c.Conn.WriteError("Err invalid DB index")
+				return
+			}
 
-		c.SessionSet("namespace", fmt.Sprintf("/%d/", i))
+			c.SessionSet("namespace", fmt.Sprintf("/%d/", i))
+			c.Client().SetDB(i)
 
-		c.Conn.WriteString("OK")
+			c.Conn.WriteString("OK")
+		},
 	})
 
 	// GET <key> [DELETE]
-	HandleFunc("get", func(c *Context) {
-		if c.Argc < 1 {
-			c.Conn.WriteError("Err invalid arguments specified")
-			return
-		}
-
-		delete := false
+	Register(&Command{
+		Name:       "get",
+		Arity:      -2,
+		Flags:      []string{FlagReadOnly, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Categories: []string{"@read", "@string", "@fast"},
+		Group:      "string",
+		Summary:    "Returns the string value of a key, optionally deleting it",
+		Arguments: []*Argument{
+			{Name: "key", Type: ArgKey},
+			{Name: "delete", Type: ArgPureToken, Token: "DELETE", Optional: true},
+		},
+		WriteIf: isDeletingGet,
+		Handler: func(c *Context) {
+			delete := isDeletingGet(c.Argv)
+
+			ret, err := c.Engine.Read(&contract.ReadInput{
+				Key:    c.AbsoluteKeyPath(c.Argv[0]),
+				Delete: delete,
+			})
 
-		for i := 1; i < c.Argc; i++ {
-			switch strings.ToLower(string(c.Argv[i])) {
-			case "delete":
-				delete = true
+			if err != nil {
+				c.Conn.WriteError("Err " + err.Error())
+				return
 			}
-		}
 
-		ret, err := c.Engine.Read(&contract.ReadInput{
-			Key:    c.AbsoluteKeyPath(c.Argv[0]),
-			Delete: delete,
-		})
-
-		if err != nil {
-			c.Conn.WriteError("Err " + err.Error())
-			return
-		}
+			if len(ret.Value) < 1 {
+				stats.KeyspaceMiss()
+				c.Writer().WriteNull()
+				return
+			}
 
-		if len(ret.Value) < 1 {
-			c.Conn.WriteNull()
-			return
-		}
+			stats.KeyspaceHit()
 
-		c.Conn.WriteBulk(ret.Value)
+			c.Conn.WriteBulk(ret.Value)
+		},
 	})
 
 	// GETDEL <key> =
 	// same as: GET <key> DELETE
-	HandleFunc("getdel", func(c *Context) {
-		if c.Argc != 1 {
-			c.Conn.WriteError("Err invalid number of arguments specified")
-			return
-		}
-
-		c.Argc++
-		c.Argv = append(c.Argv, []byte("DELETE"))
-
-		Call("get", c)
+	Register(&Command{
+		Name:       "getdel",
+		Arity:      2,
+		Flags:      []string{FlagWrite, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Categories: []string{"@write", "@string", "@fast"},
+		Group:      "string",
+		Summary:    "Returns the string value of a key after deleting the key",
+		Arguments: []*Argument{
+			{Name: "key", Type: ArgKey},
+		},
+		Handler: func(c *Context) {
+			c.Argc++
+			c.Argv = append(c.Argv, []byte("DELETE"))
+
+			Call("get", c)
+		},
 	})
 
 	// SET <key> <value> [EX seconds | KEEPTTL] [NX]
-	HandleFunc("set", func(c *Context) {
-		if c.Argc < 2 {
-			c.Conn.WriteError("Err invalid arguments specified")
-			return
-		}
-
-		writeOpts := contract.WriteInput{
-			Key:   c.AbsoluteKeyPath(c.Argv[0]),
-			Value: c.Argv[1],
-		}
+	Register(&Command{
+		Name:       "set",
+		Arity:      -3,
+		Flags:      []string{FlagWrite, FlagDenyOOM},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Categories: []string{"@write", "@string", "@slow"},
+		Group:      "string",
+		Summary:    "Sets the string value of a key",
+		Arguments: []*Argument{
+			{Name: "key", Type: ArgKey},
+			{Name: "value", Type: ArgString},
+			{Name: "expiration", Type: ArgOneOf, Optional: true, Arguments: []*Argument{
+				{Name: "seconds", Type: ArgInteger, Token: "EX"},
+				{Name: "keepttl", Type: ArgPureToken, Token: "KEEPTTL"},
+			}},
+			{Name: "condition", Type: ArgPureToken, Token: "NX", Optional: true},
+		},
+		Handler: func(c *Context) {
+			writeOpts := contract.WriteInput{
+				Key:   c.AbsoluteKeyPath(c.Argv[0]),
+				Value: c.Argv[1],
+			}
 
-		if c.Argc > 2 {
-			for i := 0; i < len(c.Argv); i++ {
-				chr := string(bytes.ToLower(c.Argv[i]))
-				switch chr {
-				case "ex":
-					n, err := strconv.ParseInt(string(c.Argv[i+1]), 10, 64)
-					if err != nil {
-						c.Conn.WriteError("Err " + err.Error())
-						return
+			if c.Argc > 2 {
+				for i := 0; i < len(c.Argv); i++ {
+					chr := string(bytes.ToLower(c.Argv[i]))
+					switch chr {
+					case "ex":
+						n, err := strconv.ParseInt(string(c.Argv[i+1]), 10, 64)
+						if err != nil {
+							c.Conn.WriteError("Err " + err.Error())
+							return
+						}
+						writeOpts.TTL = time.Second * time.Duration(n)
+					case "keepttl":
+						writeOpts.KeepTTL = true
+					case "nx":
+						writeOpts.OnlyIfNotExists = true
 					}
-					writeOpts.TTL = time.Second * time.Duration(n)
-				case "keepttl":
-					writeOpts.KeepTTL = true
-				case "nx":
-					writeOpts.OnlyIfNotExists = true
-				}
 
+				}
 			}
-		}
 
-		if c.Cfg.Server.Redis.AsyncWrites {
-			go (func() {
+			if c.Cfg.Server.Redis.AsyncWrites {
+				if err := c.Queue.Enqueue(&writeOpts); err != nil {
+					c.Conn.WriteError("Err " + err.Error())
+					return
+				}
+			} else {
 				if _, err := c.Engine.Write(&writeOpts); err != nil {
-					log.Println("[FATAL]", err.Error())
+					c.Conn.WriteError("Err " + err.Error())
+					return
 				}
-			})()
-		} else {
-			if _, err := c.Engine.Write(&writeOpts); err != nil {
-				c.Conn.WriteError("Err " + err.Error())
-				return
 			}
-		}
 
-		c.Conn.WriteString("OK")
+			c.Conn.WriteString("OK")
+		},
 	})
 
 	// TTL <key>
-	HandleFunc("ttl", func(c *Context) {
-		if c.Argc < 1 {
-			c.Conn.WriteError("Err invalid arguments specified")
-			return
-		}
-
-		ret, err := c.Engine.Read(&contract.ReadInput{
-			Key: c.AbsoluteKeyPath(c.Argv[0]),
-		})
+	Register(&Command{
+		Name:       "ttl",
+		Arity:      2,
+		Flags:      []string{FlagReadOnly, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Categories: []string{"@read", "@keyspace", "@fast"},
+		Group:      "generic",
+		Summary:    "Returns the expiration time in milliseconds of a key",
+		Arguments: []*Argument{
+			{Name: "key", Type: ArgKey},
+		},
+		Handler: func(c *Context) {
+			ret, err := c.Engine.Read(&contract.ReadInput{
+				Key: c.AbsoluteKeyPath(c.Argv[0]),
+			})
 
-		if err != nil {
-			c.Conn.WriteError("Err " + err.Error())
-			return
-		}
+			if err != nil {
+				c.Conn.WriteError("Err " + err.Error())
+				return
+			}
 
-		if !ret.Exists {
-			c.Conn.WriteBulkString("-2")
-			return
-		}
+			if !ret.Exists {
+				c.Conn.WriteBulkString("-2")
+				return
+			}
 
-		if ret.TTL == 0 {
-			c.Conn.WriteBulkString("-1")
-			return
-		}
+			if ret.TTL == 0 {
+				c.Conn.WriteBulkString("-1")
+				return
+			}
 
-		c.Conn.WriteAny(ret.TTL.Milliseconds())
+			c.Conn.WriteAny(ret.TTL.Milliseconds())
+		},
 	})
 
 	// INCR <key> [<delta>]
-	HandleFunc("incr", func(c *Context) {
-		if c.Argc < 1 {
-			c.Conn.WriteError("Err invalid arguments specified")
-			return
-		}
-
-		delta := []byte("1")
-		if c.Argc > 1 {
-			delta = c.Argv[1]
-		}
+	Register(&Command{
+		Name:       "incr",
+		Arity:      -2,
+		Flags:      []string{FlagWrite, FlagDenyOOM, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Categories: []string{"@write", "@string", "@fast"},
+		Group:      "string",
+		Summary:    "Increments the number stored at a key by one or by the specified delta",
+		Arguments: []*Argument{
+			{Name: "key", Type: ArgKey},
+			{Name: "delta", Type: ArgInteger, Optional: true},
+		},
+		Handler: func(c *Context) {
+			delta := []byte("1")
+			if c.Argc > 1 {
+				delta = c.Argv[1]
+			}
 
-		if c.Cfg.Server.Redis.AsyncWrites {
-			go (func() {
-				if _, err := c.Engine.Write(&contract.WriteInput{
+			if c.Cfg.Server.Redis.AsyncWrites {
+				if err := c.Queue.Enqueue(&contract.WriteInput{
 					Key:       c.AbsoluteKeyPath(c.Argv[0]),
 					Value:     delta,
 					Increment: true,
 				}); err != nil {
-					log.Println("[FATAL]", err.Error())
+					c.Conn.WriteError("Err " + err.Error())
+					return
 				}
-			})()
 
-			c.Conn.WriteNull()
-			return
-		}
+				c.Writer().WriteNull()
+				return
+			}
 
-		ret, err := c.Engine.Write(&contract.WriteInput{
-			Key:       c.AbsoluteKeyPath(c.Argv[0]),
-			Value:     delta,
-			Increment: true,
-		})
+			ret, err := c.Engine.Write(&contract.WriteInput{
+				Key:       c.AbsoluteKeyPath(c.Argv[0]),
+				Value:     delta,
+				Increment: true,
+			})
 
-		if err != nil {
-			c.Conn.WriteError("Err " + err.Error())
-			return
-		}
+			if err != nil {
+				c.Conn.WriteError("Err " + err.Error())
+				return
+			}
 
-		c.Conn.WriteBulk(ret.Value)
+			c.Conn.WriteBulk(ret.Value)
+		},
 	})
 
 	// INCRBY <key> <delta>
-	HandleFunc("incrby", func(c *Context) {
-		Call("incr", c)
+	Register(&Command{
+		Name:       "incrby",
+		Arity:      3,
+		Flags:      []string{FlagWrite, FlagDenyOOM, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Categories: []string{"@write", "@string", "@fast"},
+		Group:      "string",
+		Summary:    "Increments the number stored at a key by the specified delta",
+		Arguments: []*Argument{
+			{Name: "key", Type: ArgKey},
+			{Name: "delta", Type: ArgInteger},
+		},
+		Handler: func(c *Context) {
+			Call("incr", c)
+		},
 	})
 
 	// DEL key [key ...]
-	HandleFunc("del", func(c *Context) {
-		if c.Argc < 1 {
-			c.Conn.WriteError("Err invalid arguments specified")
-			return
-		}
-
-		if c.Cfg.Server.Redis.AsyncWrites {
-			go (func() {
+	Register(&Command{
+		Name:       "del",
+		Arity:      -2,
+		Flags:      []string{FlagWrite},
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Categories: []string{"@write", "@keyspace", "@slow"},
+		Group:      "generic",
+		Summary:    "Deletes one or more keys along with the keys prefixed by them",
+		Arguments: []*Argument{
+			{Name: "key", Type: ArgKey, Multiple: true},
+		},
+		Handler: func(c *Context) {
+			if c.Cfg.Server.Redis.AsyncWrites {
 				for i := range c.Argv {
-					_, err := c.Engine.Write(&contract.WriteInput{
+					err := c.Queue.Enqueue(&contract.WriteInput{
 						Key:   c.AbsoluteKeyPath(c.Argv[i]),
 						Value: nil,
 					})
 
 					if err != nil {
-						log.Println("[FATAL]", err.Error())
+						c.Conn.WriteError("Err " + err.Error())
 						return
 					}
 				}
-			})()
 
-			c.Conn.WriteString("OK")
-			return
-		}
+				c.Conn.WriteString("OK")
+				return
+			}
 
-		for i := range c.Argv {
-			_, err := c.Engine.Write(&contract.WriteInput{
-				Key:   c.AbsoluteKeyPath(c.Argv[i]),
-				Value: nil,
-			})
+			for i := range c.Argv {
+				_, err := c.Engine.Write(&contract.WriteInput{
+					Key:   c.AbsoluteKeyPath(c.Argv[i]),
+					Value: nil,
+				})
 
-			if err != nil {
-				c.Conn.WriteError("Err " + err.Error())
-				return
+				if err != nil {
+					c.Conn.WriteError("Err " + err.Error())
+					return
+				}
 			}
-		}
 
-		c.Conn.WriteString("OK")
+			c.Conn.WriteString("OK")
+		},
 	})
 
 	// HGETALL <prefix>
-	HandleFunc("hgetall", func(c *Context) {
-		prefix := []byte("")
+	Register(&Command{
+		Name:       "hgetall",
+		Arity:      -1,
+		Flags:      []string{FlagReadOnly},
+		Categories: []string{"@read", "@keyspace", "@slow"},
+		Group:      "generic",
+		Summary:    "Returns all the keys and values having the specified prefix",
+		Arguments: []*Argument{
+			{Name: "prefix", Type: ArgString, Optional: true},
+		},
+		Handler: func(c *Context) {
+			prefix := []byte("")
+
+			if c.Argc > 0 {
+				prefix = c.Argv[0]
+			}
 
-		if c.Argc > 0 {
-			prefix = c.Argv[0]
-		}
+			var result []*contract.ReadOutput
 
-		result := map[string]string{}
+			err := c.Engine.Iterate(&contract.IteratorOpts{
+				Prefix: c.AbsoluteKeyPath(prefix),
+				Callback: func(ro *contract.ReadOutput) error {
+					result = append(result, &contract.ReadOutput{
+						Key:   []byte(strings.TrimPrefix(string(ro.Key), string(c.AbsoluteKeyPath(prefix)))),
+						Value: ro.Value,
+					})
+					return nil
+				},
+			})
 
-		err := c.Engine.Iterate(&contract.IteratorOpts{
-			Prefix: c.AbsoluteKeyPath(prefix),
-			Callback: func(ro *contract.ReadOutput) error {
-				endKey := strings.TrimPrefix(string(ro.Key), string(c.AbsoluteKeyPath(prefix)))
-				result[endKey] = string(ro.Value)
-				return nil
-			},
-		})
+			if err != nil && err != contract.ErrStopIterator {
+				c.Conn.WriteError("ERR " + err.Error())
+				return
+			}
 
-		if err != nil && err != contract.ErrStopIterator {
-			c.Conn.WriteError("ERR " + err.Error())
-		}
+			w := c.Writer()
 
-		c.Conn.WriteAny(result)
+			w.WriteMap(len(result))
+			for _, ro := range result {
+				w.WriteBulk(ro.Key)
+				w.WriteBulk(ro.Value)
+			}
+		},
 	})
 
 	// FLUSHALL
-	HandleFunc("flushall", func(c *Context) {
-		_, err := c.Engine.Write(&contract.WriteInput{
-			Key:   nil,
-			Value: nil,
-		})
-
-		if err != nil {
-			c.Conn.WriteError("Err " + err.Error())
-			return
-		}
+	Register(&Command{
+		Name:       "flushall",
+		Arity:      -1,
+		Flags:      []string{FlagWrite},
+		Categories: []string{"@write", "@keyspace", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "Removes all the keys from all the databases",
+		Handler: func(c *Context) {
+			// the pending async writes must not outlive the flush
+			c.Queue.Drain()
+
+			// the functions libraries aren't keys, so they survive the flush
+			libraries, err := scripting.DumpLibraries(c.Engine)
+			if err != nil {
+				c.Conn.WriteError("Err " + err.Error())
+				return
+			}
+
+			_, err = c.Engine.Write(&contract.WriteInput{
+				Key:   nil,
+				Value: nil,
+			})
 
-		c.Conn.WriteString("OK")
+			if err != nil {
+				c.Conn.WriteError("Err " + err.Error())
+				return
+			}
+
+			if err := scripting.RestoreLibraries(c.Engine, libraries, scripting.RestoreAppend); err != nil {
+				c.Conn.WriteError("Err " + err.Error())
+				return
+			}
+
+			c.Conn.WriteString("OK")
+		},
 	})
 
 	// FLUSHDB
-	HandleFunc("flushdb", func(c *Context) {
-		_, err := c.Engine.Write(&contract.WriteInput{
-			Key:   c.AbsoluteKeyPath(),
-			Value: nil,
-		})
-
-		if err != nil {
-			c.Conn.WriteError("Err " + err.Error())
-			return
-		}
+	Register(&Command{
+		Name:       "flushdb",
+		Arity:      -1,
+		Flags:      []string{FlagWrite},
+		Categories: []string{"@write", "@keyspace", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "Removes all the keys from the current database",
+		Handler: func(c *Context) {
+			// the pending async writes must not outlive the flush
+			c.Queue.Drain()
 
-		c.Conn.WriteString("OK")
+			_, err := c.Engine.Write(&contract.WriteInput{
+				Key:   c.AbsoluteKeyPath(),
+				Value: nil,
+			})
+
+			if err != nil {
+				c.Conn.WriteError("Err " + err.Error())
+				return
+			}
+
+			c.Conn.WriteString("OK")
+		},
 	})
 
 	// PUBLISH
-	HandleFunc("publish", func(c *Context) {
-		if c.Argc < 2 {
-			c.Conn.WriteError("ERR wrong number of arguments for 'publish' command")
-			return
-		}
-
-		if err := c.Engine.Publish(c.AbsoluteKeyPath([]byte("redix"), c.Argv[0]), c.Argv[1]); err != nil {
-			c.Conn.WriteError("ERR %s " + err.Error())
-			return
-		}
+	Register(&Command{
+		Name:       "publish",
+		Arity:      3,
+		Flags:      []string{FlagPubSub, FlagLoading, FlagStale, FlagFast},
+		Categories: []string{"@pubsub", "@fast"},
+		Group:      "pubsub",
+		Summary:    "Posts a message to a channel",
+		Arguments: []*Argument{
+			{Name: "channel", Type: ArgString},
+			{Name: "message", Type: ArgString},
+		},
+		Handler: func(c *Context) {
+			if err := c.Engine.Publish(c.AbsoluteKeyPath([]byte("redix"), c.Argv[0]), c.Argv[1]); err != nil {
+				c.Conn.WriteError("ERR %s " + err.Error())
+				return
+			}
 
-		c.Conn.WriteInt(0)
+			c.Conn.WriteInt(0)
+		},
 	})
 
-	HandleFunc("subscribe", func(c *Context) {
-		if c.Argc != 1 {
-			c.Conn.WriteError("ERR wrong number of arguments for 'subscribe' command")
-			return
-		}
+	Register(&Command{
+		Name:       "subscribe",
+		Arity:      2,
+		Flags:      []string{FlagPubSub, FlagNoScript, FlagLoading, FlagStale},
+		Categories: []string{"@pubsub", "@slow"},
+		Group:      "pubsub",
+		Summary:    "Listens for the messages published to a channel",
+		Arguments: []*Argument{
+			{Name: "channel", Type: ArgString},
+		},
+		Handler: func(c *Context) {
+			conn := c.Conn.Detach()
+			defer conn.Close()
+
+			w := NewWriter(conn, c.Protocol())
+
+			channel := c.AbsoluteKeyPath([]byte("redix"), c.Argv[0])
+
+			c.Client().AddSubscriptions(1)
+			defer c.Client().AddSubscriptions(-1)
+
+			stats.ChannelSubscribed(string(channel))
+			defer stats.ChannelUnsubscribed(string(channel))
+
+			w.WritePush(3)
+			w.WriteBulkString("subscribe")
+			w.WriteBulk(c.Argv[0])
+			w.WriteInt(1)
+			conn.Flush()
 
-		conn := c.Conn.Detach()
-		defer conn.Close()
+			err := c.Engine.Subscribe(channel, func(msg []byte) error {
+				w.WritePush(3)
+				w.WriteBulkString("message")
+				w.WriteBulk(c.Argv[0])
+				w.WriteBulk(msg)
+				return conn.Flush()
+			})
 
-		conn.WriteArray(3)
-		conn.WriteBulkString("subscribe")
-		conn.WriteBulk(c.Argv[0])
-		conn.WriteInt(1)
-		conn.Flush()
+			if err != nil {
+				c.Conn.WriteError("ERR " + err.Error())
+				return
+			}
+		},
+	})
+}
 
-		err := c.Engine.Subscribe(c.AbsoluteKeyPath([]byte("redix"), c.Argv[0]), func(msg []byte) error {
-			conn.WriteArray(3)
-			conn.WriteBulkString("message")
-			conn.WriteBulk(c.Argv[0])
-			conn.WriteBulk(msg)
-			conn.Flush()
-			return nil
-		})
+// isDeletingGet whether the GET arguments include the DELETE option or not
+func isDeletingGet(argv [][]byte) bool {
+	if len(argv) < 2 {
+		return false
+	}
 
-		if err != nil {
-			c.Conn.WriteError("ERR " + err.Error())
-			return
+	for _, arg := range argv[1:] {
+		if strings.ToLower(string(arg)) == "delete" {
+			return true
 		}
-	})
+	}
+
+	return false
 }
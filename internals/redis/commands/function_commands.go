@@ -0,0 +1,266 @@
+package commands
+
+import (
+	"path"
+	"strings"
+
+	"github.com/alash3al/redix/internals/scripting"
+)
+
+func init() {
+	fcallArguments := []*Argument{
+		{Name: "function", Type: ArgString},
+		{Name: "numkeys", Type: ArgInteger},
+		{Name: "key", Type: ArgKey, Optional: true, Multiple: true},
+		{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+	}
+
+	// FCALL <function> <numkeys> [<key> ...] [<arg> ...]
+	Register(&Command{
+		Name:       "fcall",
+		Arity:      -3,
+		Flags:      []string{FlagNoScript, FlagStale},
+		Categories: []string{"@slow", "@scripting"},
+		Group:      "scripting",
+		Summary:    "Invokes a function",
+		Arguments:  fcallArguments,
+		Exclusive:  true,
+		Handler: func(c *Context) {
+			fcall(c, false)
+		},
+	})
+
+	// FCALL_RO <function> <numkeys> [<key> ...] [<arg> ...]
+	Register(&Command{
+		Name:       "fcall_ro",
+		Arity:      -3,
+		Flags:      []string{FlagNoScript, FlagStale},
+		Categories: []string{"@slow", "@scripting"},
+		Group:      "scripting",
+		Summary:    "Invokes a read-only function",
+		Arguments:  fcallArguments,
+		Exclusive:  true,
+		Handler: func(c *Context) {
+			fcall(c, true)
+		},
+	})
+
+	// FUNCTION LOAD [REPLACE] <code> | DELETE <library> | FLUSH [ASYNC | SYNC] | KILL
+	// | LIST [LIBRARYNAME <pattern>] [WITHCODE] | DUMP | RESTORE <payload> [FLUSH | APPEND | REPLACE]
+	Register(&Command{
+		Name:       "function",
+		Arity:      -2,
+		Flags:      []string{FlagNoScript},
+		Categories: []string{"@slow", "@scripting"},
+		Group:      "scripting",
+		Summary:    "Manages the functions libraries",
+		Arguments: []*Argument{
+			{Name: "subcommand", Type: ArgString},
+			{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+		},
+		WriteIf: func(argv [][]byte) bool {
+			switch strings.ToLower(string(argv[0])) {
+			case "load", "delete", "flush", "restore":
+				return true
+			}
+
+			return false
+		},
+		Handler: func(c *Context) {
+			subcommand := strings.ToLower(string(c.Argv[0]))
+
+			switch subcommand {
+			case "load":
+				functionLoad(c)
+			case "delete":
+				if c.Argc != 2 {
+					c.Conn.WriteError("ERR wrong number of arguments for 'function|delete' command")
+					return
+				}
+
+				if err := scripting.DeleteLibrary(c.Engine, string(c.Argv[1])); err != nil {
+					c.Conn.WriteError("ERR " + err.Error())
+					return
+				}
+
+				c.Conn.WriteString("OK")
+			case "flush":
+				if err := scripting.FlushLibraries(c.Engine); err != nil {
+					c.Conn.WriteError("ERR " + err.Error())
+					return
+				}
+
+				c.Conn.WriteString("OK")
+			case "kill":
+				if err := scripting.Kill(); err != nil {
+					c.Conn.WriteError(err.Error())
+					return
+				}
+
+				c.Conn.WriteString("OK")
+			case "list":
+				functionList(c)
+			case "dump":
+				payload, err := scripting.DumpLibraries(c.Engine)
+				if err != nil {
+					c.Conn.WriteError("ERR " + err.Error())
+					return
+				}
+
+				c.Conn.WriteBulk(payload)
+			case "restore":
+				functionRestore(c)
+			default:
+				c.Conn.WriteError("ERR unknown subcommand '" + subcommand + "'")
+			}
+		},
+	})
+}
+
+// fcall runs the function having the name specified in the first argument, the functions
+// that may write can't be called read-only and the ones flagged as no-writes can't write.
+func fcall(c *Context, readOnly bool) {
+	lib, fn, err := scripting.FindFunction(c.Engine, string(c.Argv[0]))
+	if err != nil {
+		c.Conn.WriteError("ERR " + err.Error())
+		return
+	}
+
+	if fn == nil {
+		c.Conn.WriteError("ERR Function not found")
+		return
+	}
+
+	if readOnly && !fn.ReadOnly() {
+		c.Conn.WriteError("ERR Can not execute a script with write flag using *_ro command.")
+		return
+	}
+
+	runScript(c, fn.ReadOnly(), func(opts *scripting.Options) (interface{}, error) {
+		return scripting.RunFunction(lib, fn.Name, opts)
+	})
+}
+
+// functionLoad handles FUNCTION LOAD [REPLACE] <code>
+func functionLoad(c *Context) {
+	replace := c.Argc == 3 && strings.EqualFold(string(c.Argv[1]), "replace")
+
+	if c.Argc != 2 && !replace {
+		c.Conn.WriteError("ERR syntax error")
+		return
+	}
+
+	name, err := scripting.LoadLibrary(c.Engine, c.Argv[c.Argc-1], replace)
+	if err != nil {
+		c.Conn.WriteError("ERR " + err.Error())
+		return
+	}
+
+	c.Conn.WriteBulkString(name)
+}
+
+// functionList handles FUNCTION LIST [LIBRARYNAME <pattern>] [WITHCODE]
+func functionList(c *Context) {
+	pattern, withCode := "*", false
+
+	for i := 1; i < c.Argc; i++ {
+		switch strings.ToLower(string(c.Argv[i])) {
+		case "withcode":
+			withCode = true
+		case "libraryname":
+			if i+1 >= c.Argc {
+				c.Conn.WriteError("ERR syntax error")
+				return
+			}
+
+			i++
+			pattern = string(c.Argv[i])
+		default:
+			c.Conn.WriteError("ERR syntax error")
+			return
+		}
+	}
+
+	libs, err := scripting.Libraries(c.Engine)
+	if err != nil {
+		c.Conn.WriteError("ERR " + err.Error())
+		return
+	}
+
+	var matched []*scripting.Library
+
+	for _, lib := range libs {
+		if ok, _ := path.Match(pattern, lib.Name); ok {
+			matched = append(matched, lib)
+		}
+	}
+
+	w := c.Writer()
+
+	w.WriteArray(len(matched))
+
+	for _, lib := range matched {
+		fields := 3
+		if withCode {
+			fields++
+		}
+
+		w.WriteMap(fields)
+		w.WriteBulkString("library_name")
+		w.WriteBulkString(lib.Name)
+		w.WriteBulkString("engine")
+		w.WriteBulkString("LUA")
+		w.WriteBulkString("functions")
+		w.WriteArray(len(lib.Functions))
+
+		for _, fn := range lib.Functions {
+			w.WriteMap(3)
+			w.WriteBulkString("name")
+			w.WriteBulkString(fn.Name)
+			w.WriteBulkString("description")
+			if fn.Description == "" {
+				w.WriteNull()
+			} else {
+				w.WriteBulkString(fn.Description)
+			}
+			w.WriteBulkString("flags")
+			w.WriteSet(len(fn.Flags))
+			for _, flag := range fn.Flags {
+				w.WriteString(flag)
+			}
+		}
+
+		if withCode {
+			w.WriteBulkString("library_code")
+			w.WriteBulk(lib.Source)
+		}
+	}
+}
+
+// functionRestore handles FUNCTION RESTORE <payload> [FLUSH | APPEND | REPLACE]
+func functionRestore(c *Context) {
+	if c.Argc < 2 || c.Argc > 3 {
+		c.Conn.WriteError("ERR wrong number of arguments for 'function|restore' command")
+		return
+	}
+
+	policy := scripting.RestoreAppend
+
+	if c.Argc == 3 {
+		policy = strings.ToLower(string(c.Argv[2]))
+
+		switch policy {
+		case scripting.RestoreAppend, scripting.RestoreReplace, scripting.RestoreFlush:
+		default:
+			c.Conn.WriteError("ERR Wrong restore policy given, value should be either FLUSH, APPEND or REPLACE.")
+			return
+		}
+	}
+
+	if err := scripting.RestoreLibraries(c.Engine, c.Argv[1], policy); err != nil {
+		c.Conn.WriteError("ERR " + err.Error())
+		return
+	}
+
+	c.Conn.WriteString("OK")
+}
@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/replication"
+	"github.com/alash3al/redix/internals/stats"
+)
+
+// infoSection represents a section of the INFO command output
+type infoSection struct {
+	name      string
+	title     string
+	isDefault bool
+	fn        func(*infoContext) ([]string, error)
+}
+
+// infoContext holds the data shared between the INFO sections
+type infoContext struct {
+	*Context
+
+	snapshot    *stats.Snapshot
+	engineStats map[string]interface{}
+	engineErr   error
+	engineRead  bool
+}
+
+var (
+	infoSections = []infoSection{
+		{name: "server", title: "Server", isDefault: true, fn: infoServer},
+		{name: "clients", title: "Clients", isDefault: true, fn: infoClients},
+		{name: "stats", title: "Stats", isDefault: true, fn: infoStats},
+		{name: "commandstats", title: "Commandstats", isDefault: false, fn: infoCommandStats},
+		{name: "replication", title: "Replication", isDefault: true, fn: infoReplication},
+		{name: "keyspace", title: "Keyspace", isDefault: true, fn: infoKeyspace},
+		{name: "engine", title: "Engine", isDefault: true, fn: infoEngine},
+	}
+)
+
+func init() {
+	// INFO [section [section ...]]
+	Register(&Command{
+		Name:       "info",
+		Arity:      -1,
+		Flags:      []string{FlagLoading, FlagStale},
+		Categories: []string{"@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "Returns the information and statistics about the server",
+		Arguments: []*Argument{
+			{Name: "section", Type: ArgString, Optional: true, Multiple: true},
+		},
+		Handler: func(c *Context) {
+			requested := map[string]bool{}
+			for _, arg := range c.Argv {
+				requested[strings.ToLower(string(arg))] = true
+			}
+
+			all := requested["all"] || requested["everything"]
+			defaults := len(requested) < 1 || requested["default"]
+
+			ictx := &infoContext{
+				Context:  c,
+				snapshot: stats.Take(),
+			}
+
+			var sections []string
+
+			for _, section := range infoSections {
+				if !all && !requested[section.name] && !(defaults && section.isDefault) {
+					continue
+				}
+
+				lines, err := section.fn(ictx)
+				if err != nil {
+					c.Conn.WriteError("ERR " + err.Error())
+					return
+				}
+
+				sections = append(sections, "# "+section.title+"\r\n"+strings.Join(lines, "\r\n")+"\r\n")
+			}
+
+			c.Conn.WriteBulkString(strings.Join(sections, "\r\n"))
+		},
+	})
+}
+
+// engineReport fetches (once) the stats reported by the engine if it supports that
+func (ic *infoContext) engineReport() (map[string]interface{}, error) {
+	if !ic.engineRead {
+		ic.engineRead = true
+
+		if reporter, ok := ic.Engine.(contract.Reporter); ok {
+			ic.engineStats, ic.engineErr = reporter.Stats()
+		}
+	}
+
+	return ic.engineStats, ic.engineErr
+}
+
+func infoServer(ic *infoContext) ([]string, error) {
+	uptime := time.Since(ic.snapshot.StartedAt)
+
+	return []string{
+		"redix_version:" + Version,
+		"os:" + runtime.GOOS,
+		"arch:" + runtime.GOARCH,
+		"go_version:" + runtime.Version(),
+		fmt.Sprintf("process_id:%d", os.Getpid()),
+		fmt.Sprintf("uptime_in_seconds:%d", int64(uptime.Seconds())),
+		fmt.Sprintf("uptime_in_days:%d", int64(uptime.Hours()/24)),
+		"engine:" + ic.Cfg.DefaultEngine().Driver,
+	}, nil
+}
+
+func infoClients(ic *infoContext) ([]string, error) {
+	clients := Clients()
+	pubsubClients := 0
+
+	for _, client := range clients {
+		if client.Subscriptions() > 0 {
+			pubsubClients++
+		}
+	}
+
+	return []string{
+		fmt.Sprintf("connected_clients:%d", len(clients)),
+		fmt.Sprintf("pubsub_clients:%d", pubsubClients),
+		fmt.Sprintf("maxclients:%d", ic.Cfg.Server.Redis.MaxConns),
+	}, nil
+}
+
+func infoStats(ic *infoContext) ([]string, error) {
+	engineStats, err := ic.engineReport()
+	if err != nil {
+		return nil, err
+	}
+
+	expiredKeys, ok := engineStats["expired_keys"]
+	if !ok {
+		expiredKeys = 0
+	}
+
+	return []string{
+		fmt.Sprintf("total_connections_received:%d", ic.snapshot.ConnectionsReceived),
+		fmt.Sprintf("total_commands_processed:%d", ic.snapshot.CommandsProcessed),
+		fmt.Sprintf("total_error_replies:%d", ic.snapshot.ErrorReplies),
+		fmt.Sprintf("keyspace_hits:%d", ic.snapshot.KeyspaceHits),
+		fmt.Sprintf("keyspace_misses:%d", ic.snapshot.KeyspaceMisses),
+		fmt.Sprintf("expired_keys:%v", expiredKeys),
+		fmt.Sprintf("pubsub_channels:%d", ic.snapshot.PubSubChannels),
+	}, nil
+}
+
+func infoCommandStats(ic *infoContext) ([]string, error) {
+	var lines []string
+
+	for _, cmd := range ic.snapshot.Commands {
+		lines = append(lines, fmt.Sprintf(
+			"cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f,failed_calls=%d",
+			cmd.Name,
+			cmd.Calls,
+			cmd.Usec,
+			float64(cmd.Usec)/float64(cmd.Calls),
+			cmd.FailedCalls,
+		))
+	}
+
+	return lines, nil
+}
+
+func infoReplication(ic *infoContext) ([]string, error) {
+	if replica, ok := replication.CurrentReplica(); ok {
+		host, port, _ := net.SplitHostPort(replica.Addr)
+		state, offset := replica.State()
+
+		linkStatus, syncInProgress := "down", 0
+		if state == replication.StateConnected {
+			linkStatus = "up"
+		} else if state == replication.StateSync {
+			syncInProgress = 1
+		}
+
+		return []string{
+			"role:slave",
+			"master_host:" + host,
+			"master_port:" + port,
+			"master_link_status:" + linkStatus,
+			fmt.Sprintf("master_sync_in_progress:%d", syncInProgress),
+			fmt.Sprintf("slave_repl_offset:%d", offset),
+		}, nil
+	}
+
+	replicas := replication.Replicas()
+
+	lines := []string{
+		"role:master",
+		fmt.Sprintf("connected_slaves:%d", len(replicas)),
+	}
+
+	for i, replica := range replicas {
+		host, port, _ := net.SplitHostPort(replica.Addr)
+		lines = append(lines, fmt.Sprintf("slave%d:ip=%s,port=%s,state=online,offset=%d", i, host, port, replica.Offset()))
+	}
+
+	return append(lines,
+		"master_replid:"+replication.ReplID(),
+		fmt.Sprintf("master_repl_offset:%d", replication.Offset()),
+	), nil
+}
+
+func infoKeyspace(ic *infoContext) ([]string, error) {
+	reporter, ok := ic.Engine.(contract.Reporter)
+	if !ok {
+		return nil, nil
+	}
+
+	keyspace, err := reporter.Keyspace()
+	if err != nil {
+		return nil, err
+	}
+
+	dbs := make([]int, 0, len(keyspace))
+	for db := range keyspace {
+		dbs = append(dbs, db)
+	}
+
+	sort.Ints(dbs)
+
+	var lines []string
+
+	for _, db := range dbs {
+		lines = append(lines, fmt.Sprintf("db%d:keys=%d,expires=%d", db, keyspace[db].Keys, keyspace[db].Expires))
+	}
+
+	return lines, nil
+}
+
+func infoEngine(ic *infoContext) ([]string, error) {
+	engineStats, err := ic.engineReport()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(engineStats))
+	for k := range engineStats {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	lines := []string{"driver:" + ic.Cfg.DefaultEngine().Driver}
+
+	if len(ic.Cfg.Engines) > 1 {
+		engines := make([]string, 0, len(ic.Cfg.Engines))
+		for _, engineCfg := range ic.Cfg.Engines {
+			engines = append(engines, engineCfg.Name+"="+engineCfg.Driver)
+		}
+
+		lines = append(lines, "engines:"+strings.Join(engines, ","))
+	}
+
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s:%v", k, engineStats[k]))
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Global consts
+const (
+	monitorBufferSize = 1024
+)
+
+// monitor represents a client running the MONITOR command
+type monitor struct {
+	lines      chan string
+	overflowed chan struct{}
+	once       sync.Once
+}
+
+var (
+	monitorsMap     = map[*monitor]struct{}{}
+	monitorsMapLock = &sync.RWMutex{}
+	monitorsCount   int32
+)
+
+// MonitorFeed sends the specified command to all the active monitors,
+// a monitor that can't keep up with the commands rate gets disconnected
+func MonitorFeed(name string, c *Context) {
+	if atomic.LoadInt32(&monitorsCount) < 1 {
+		return
+	}
+
+	args := make([]string, 0, c.Argc+1)
+	args = append(args, strconv.Quote(name))
+	for _, arg := range c.Argv {
+		args = append(args, strconv.Quote(string(arg)))
+	}
+
+	client := c.Client()
+
+	client.RLock()
+	db := client.db
+	client.RUnlock()
+
+	now := time.Now()
+	line := fmt.Sprintf("%d.%06d [%d %s] %s", now.Unix(), now.Nanosecond()/1000, db, client.Addr, strings.Join(args, " "))
+
+	monitorsMapLock.RLock()
+	defer monitorsMapLock.RUnlock()
+
+	for m := range monitorsMap {
+		select {
+		case m.lines <- line:
+		default:
+			m.once.Do(func() {
+				close(m.overflowed)
+			})
+		}
+	}
+}
+
+func init() {
+	// MONITOR
+	Register(&Command{
+		Name:       "monitor",
+		Arity:      1,
+		Flags:      []string{FlagAdmin, FlagNoScript, FlagLoading, FlagStale},
+		Categories: []string{"@admin", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "Streams every command processed by the server",
+		Handler: func(c *Context) {
+			m := &monitor{
+				lines:      make(chan string, monitorBufferSize),
+				overflowed: make(chan struct{}),
+			}
+
+			conn := c.Conn.Detach()
+			defer conn.Close()
+
+			conn.WriteString("OK")
+			if err := conn.Flush(); err != nil {
+				return
+			}
+
+			monitorsMapLock.Lock()
+			monitorsMap[m] = struct{}{}
+			atomic.AddInt32(&monitorsCount, 1)
+			monitorsMapLock.Unlock()
+
+			defer (func() {
+				monitorsMapLock.Lock()
+				delete(monitorsMap, m)
+				atomic.AddInt32(&monitorsCount, -1)
+				monitorsMapLock.Unlock()
+			})()
+
+			// the only commands a monitor may send are the ones that end it
+			done := make(chan struct{})
+			go (func() {
+				defer close(done)
+				for {
+					cmd, err := conn.ReadCommand()
+					if err != nil {
+						return
+					}
+
+					switch strings.ToLower(string(cmd.Args[0])) {
+					case "quit", "reset":
+						return
+					}
+				}
+			})()
+
+			for {
+				select {
+				case line := <-m.lines:
+					conn.WriteString(line)
+
+					// drain whatever is buffered before hitting the network
+					for i := len(m.lines); i > 0; i-- {
+						conn.WriteString(<-m.lines)
+					}
+
+					if err := conn.Flush(); err != nil {
+						return
+					}
+				case <-m.overflowed:
+					return
+				case <-done:
+					return
+				}
+			}
+		},
+	})
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/plugins"
+)
+
+// RegisterPlugin registers the commands provided by the specified plugin
+func RegisterPlugin(p *plugins.Plugin) error {
+	for _, name := range p.Commands {
+		if _, exists := Lookup(name); exists {
+			return fmt.Errorf("the command (%s) of the plugin (%s) already exists", name, p.Name)
+		}
+	}
+
+	for _, name := range p.Commands {
+		name := name
+
+		// the plugins may write, so they are flagged as writes (they wait for the scripts, are paused
+		// with the writes and rejected by the read-only nodes) and aren't allowed in the (possibly read-only) scripts
+		Register(&Command{
+			Name:       name,
+			Arity:      -1,
+			Flags:      []string{FlagWrite, FlagNoScript},
+			Categories: []string{"@write", "@slow"},
+			Group:      "module",
+			Summary:    "Provided by the " + p.Name + " plugin",
+			Handler: func(c *Context) {
+				pluginCall(c, p, name)
+			},
+		})
+	}
+
+	return nil
+}
+
+// pluginCall executes the specified plugin command, its reply is only sent once it completes
+func pluginCall(c *Context, p *plugins.Plugin, name string) {
+	host := &pluginHost{c: c}
+
+	if err := p.Call(name, c.Argv, host); err != nil {
+		c.Conn.WriteError("ERR " + err.Error())
+		return
+	}
+
+	if len(host.buf) < 1 {
+		c.Conn.WriteNull()
+		return
+	}
+
+	if _, rest, err := parseReply(host.buf); err != nil || len(rest) > 0 {
+		c.Conn.WriteError("ERR the command (" + name + ") must reply exactly once")
+		return
+	}
+
+	c.Conn.WriteRaw(host.buf)
+}
+
+// pluginHost records the plugin command reply and accesses the keys within the caller's namespace
+type pluginHost struct {
+	recorder
+
+	c *Context
+}
+
+// Read returns the value of the specified key
+func (h *pluginHost) Read(key []byte) ([]byte, bool, error) {
+	ro, err := h.c.Engine.Read(&contract.ReadInput{
+		Key: h.c.AbsoluteKeyPath(key),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return ro.Value, ro.Exists, nil
+}
+
+// Write sets the value of the specified key
+func (h *pluginHost) Write(key, value []byte, ttl time.Duration) error {
+	_, err := h.c.Engine.Write(&contract.WriteInput{
+		Key:   h.c.AbsoluteKeyPath(key),
+		Value: value,
+		TTL:   ttl,
+	})
+
+	return err
+}
+
+// Delete deletes the specified key
+func (h *pluginHost) Delete(key []byte) (bool, error) {
+	ro, err := h.c.Engine.Read(&contract.ReadInput{
+		Key:    h.c.AbsoluteKeyPath(key),
+		Delete: true,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return ro != nil && ro.Exists, nil
+}
+
+// Iterate calls fn for each key having the specified prefix
+func (h *pluginHost) Iterate(prefix []byte, fn func(key, value []byte) (bool, error)) error {
+	namespace := h.c.AbsoluteKeyPath()
+
+	// the entries are collected first, as fn may access the engine
+	var result []*contract.ReadOutput
+
+	err := h.c.Engine.Iterate(&contract.IteratorOpts{
+		Prefix: h.c.AbsoluteKeyPath(prefix),
+		Callback: func(ro *contract.ReadOutput) error {
+			result = append(result, ro)
+			return nil
+		},
+	})
+
+	if err != nil && err != contract.ErrStopIterator {
+		return err
+	}
+
+	for _, ro := range result {
+		more, err := fn(bytes.TrimPrefix(ro.Key, namespace), ro.Value)
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return nil
+}
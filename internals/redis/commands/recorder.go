@@ -0,0 +1,173 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/alash3al/redix/internals/scripting"
+	"github.com/tidwall/redcon"
+)
+
+// recorder is a redcon.Conn recording the reply of a command called by a script
+type recorder struct {
+	buf     []byte
+	session interface{}
+}
+
+// RemoteAddr returns the remote address of the script "client"
+func (r *recorder) RemoteAddr() string {
+	return "lua"
+}
+
+// Close does nothing, the scripts can't close their callers connections
+func (r *recorder) Close() error {
+	return nil
+}
+
+// WriteError records an error reply
+func (r *recorder) WriteError(msg string) {
+	r.buf = redcon.AppendError(r.buf, msg)
+}
+
+// WriteString records a status reply
+func (r *recorder) WriteString(str string) {
+	r.buf = redcon.AppendString(r.buf, str)
+}
+
+// WriteBulk records a bulk reply
+func (r *recorder) WriteBulk(bulk []byte) {
+	r.buf = redcon.AppendBulk(r.buf, bulk)
+}
+
+// WriteBulkString records a bulk reply
+func (r *recorder) WriteBulkString(bulk string) {
+	r.buf = redcon.AppendBulkString(r.buf, bulk)
+}
+
+// WriteInt records an integer reply
+func (r *recorder) WriteInt(num int) {
+	r.buf = redcon.AppendInt(r.buf, int64(num))
+}
+
+// WriteInt64 records an integer reply
+func (r *recorder) WriteInt64(num int64) {
+	r.buf = redcon.AppendInt(r.buf, num)
+}
+
+// WriteUint64 records an integer reply
+func (r *recorder) WriteUint64(num uint64) {
+	r.buf = redcon.AppendUint(r.buf, num)
+}
+
+// WriteArray records an array header
+func (r *recorder) WriteArray(count int) {
+	r.buf = redcon.AppendArray(r.buf, count)
+}
+
+// WriteNull records a null reply
+func (r *recorder) WriteNull() {
+	r.buf = redcon.AppendNull(r.buf)
+}
+
+// WriteRaw records a raw reply
+func (r *recorder) WriteRaw(data []byte) {
+	r.buf = append(r.buf, data...)
+}
+
+// WriteAny records a reply of any type
+func (r *recorder) WriteAny(v interface{}) {
+	r.buf = redcon.AppendAny(r.buf, v)
+}
+
+// Context returns the session of the script
+func (r *recorder) Context() interface{} {
+	return r.session
+}
+
+// SetContext sets the session of the script
+func (r *recorder) SetContext(v interface{}) {
+	r.session = v
+}
+
+// SetReadBuffer does nothing
+func (r *recorder) SetReadBuffer(int) {}
+
+// Detach panics, the commands that detach the connection aren't allowed in scripts
+func (r *recorder) Detach() redcon.DetachedConn {
+	panic(fmt.Errorf("the scripts connections can't be detached"))
+}
+
+// ReadPipeline returns nothing, the scripts don't pipeline
+func (r *recorder) ReadPipeline() []redcon.Command {
+	return nil
+}
+
+// PeekPipeline returns nothing, the scripts don't pipeline
+func (r *recorder) PeekPipeline() []redcon.Command {
+	return nil
+}
+
+// NetConn returns nothing, the scripts have no underlying connection
+func (r *recorder) NetConn() net.Conn {
+	return nil
+}
+
+// reply parses the recorded reply
+func (r *recorder) reply() interface{} {
+	reply, _, err := parseReply(r.buf)
+	if err != nil {
+		return scripting.ErrorReply("ERR unable to parse the command reply: " + err.Error())
+	}
+
+	return reply
+}
+
+// parseReply parses the first RESP2 reply in the specified data then returns it along with the rest of the data
+func parseReply(data []byte) (interface{}, []byte, error) {
+	end := bytes.Index(data, []byte("\r\n"))
+	if end < 1 {
+		return nil, nil, fmt.Errorf("incomplete reply")
+	}
+
+	line, rest := string(data[1:end]), data[end+2:]
+
+	switch data[0] {
+	case '+':
+		return scripting.Status(line), rest, nil
+	case '-':
+		return scripting.ErrorReply(line), rest, nil
+	case ':':
+		n, err := strconv.ParseInt(line, 10, 64)
+		return n, rest, err
+	case '$':
+		size, err := strconv.Atoi(line)
+		if err != nil || size < 0 {
+			return nil, rest, err
+		}
+
+		if len(rest) < size+2 {
+			return nil, nil, fmt.Errorf("incomplete reply")
+		}
+
+		return rest[:size], rest[size+2:], nil
+	case '*':
+		size, err := strconv.Atoi(line)
+		if err != nil || size < 0 {
+			return nil, rest, err
+		}
+
+		items := make([]interface{}, size)
+
+		for i := range items {
+			if items[i], rest, err = parseReply(rest); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return items, rest, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported reply type (%c)", data[0])
+	}
+}
@@ -2,46 +2,278 @@ package commands
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+
+	"github.com/alash3al/redix/internals/replication"
+	"github.com/alash3al/redix/internals/scripting"
+)
+
+// the command flags as reported by COMMAND INFO
+const (
+	FlagWrite    = "write"
+	FlagReadOnly = "readonly"
+	FlagDenyOOM  = "denyoom"
+	FlagAdmin    = "admin"
+	FlagPubSub   = "pubsub"
+	FlagNoScript = "noscript"
+	FlagLoading  = "loading"
+	FlagStale    = "stale"
+	FlagFast     = "fast"
+)
+
+// the argument types as reported by COMMAND DOCS
+const (
+	ArgString    = "string"
+	ArgInteger   = "integer"
+	ArgKey       = "key"
+	ArgPureToken = "pure-token"
+	ArgOneOf     = "oneof"
+	ArgBlock     = "block"
 )
 
 // Handler a command handler func
 type Handler func(*Context)
 
+// WriteChecker reports whether a call of a command with the specified arguments modifies the data or not
+type WriteChecker func(argv [][]byte) bool
+
+// Argument describes an argument of a command
+type Argument struct {
+	Name      string
+	Type      string
+	Token     string
+	Optional  bool
+	Multiple  bool
+	Arguments []*Argument
+}
+
+// Command represents a registered command along with its metadata
+type Command struct {
+	// Name the name of the command
+	Name string
+
+	// Arity the number of the arguments including the command name, -N means N or more arguments
+	Arity int
+
+	// Flags the command flags (FlagWrite, FlagReadOnly, ...)
+	Flags []string
+
+	// FirstKey, LastKey and KeyStep the positions of the keys in the arguments including the command name,
+	// a negative LastKey counts from the last argument, no keys are accepted if FirstKey is 0.
+	FirstKey int
+	LastKey  int
+	KeyStep  int
+
+	// Categories the ACL categories of the command
+	Categories []string
+
+	// Group, Summary and Arguments document the command
+	Group     string
+	Summary   string
+	Arguments []*Argument
+
+	// WriteIf marks the calls of a read-only command it returns true for as writes
+	WriteIf WriteChecker
+
+	// Exclusive whether the command must run alone (i.e the scripts), the commands touching the data wait for it
+	Exclusive bool
+
+	// Handler the command handler
+	Handler Handler
+}
+
 var (
-	commandsMap     = map[string]Handler{}
+	commandsMap     = map[string]*Command{}
 	commandsMapLock = &sync.RWMutex{}
+
+	// exclusiveLock is held exclusively by the exclusive commands and shared by the ones touching the data
+	exclusiveLock = &sync.RWMutex{}
 )
 
-// HandleFunc reigtser a command handler
-func HandleFunc(name string, fn Handler) {
+// Register registers the specified command
+func Register(cmd *Command) {
 	commandsMapLock.Lock()
 	defer commandsMapLock.Unlock()
 
-	name = strings.ToLower(name)
+	cmd.Name = strings.ToLower(cmd.Name)
+
+	if _, exists := commandsMap[cmd.Name]; exists {
+		panic(fmt.Errorf("command '%s' already exists", cmd.Name))
+	}
 
-	if _, exists := commandsMap[name]; exists {
-		panic(fmt.Errorf("command '%s' already exists", name))
+	if cmd.Handler == nil {
+		panic(fmt.Errorf("command '%s' has no handler", cmd.Name))
 	}
 
-	commandsMap[name] = fn
+	commandsMap[cmd.Name] = cmd
+}
+
+// HandleFunc reigtser a command handler that accepts any number of arguments and has no metadata
+func HandleFunc(name string, fn Handler) {
+	Register(&Command{
+		Name:    name,
+		Arity:   -1,
+		Handler: fn,
+	})
+}
+
+// Execute executes the specified command name on behalf of a client, the exclusive commands run alone,
+// the commands touching the data are rejected while a script is busy, otherwise they wait for it.
+func Execute(name string, ctx *Context) {
+	if cmd, exists := Lookup(name); exists && (cmd.Exclusive || cmd.touchesData()) {
+		if scripting.Busy() {
+			ctx.Conn.WriteError("BUSY Redis is busy running a script. You can only call SCRIPT KILL or SHUTDOWN NOSAVE.")
+			return
+		}
+
+		if cmd.Exclusive {
+			exclusiveLock.Lock()
+			defer exclusiveLock.Unlock()
+		} else {
+			exclusiveLock.RLock()
+			defer exclusiveLock.RUnlock()
+		}
+	}
+
+	Call(name, ctx)
+}
+
+// Lock holds the lock shared by the commands touching the data (exclusively if specified) till the returned function
+// is called, so the other interfaces apply many operations atomically against the engines that don't support transactions.
+func Lock(exclusive bool) (unlock func()) {
+	if exclusive {
+		exclusiveLock.Lock()
+		return exclusiveLock.Unlock
+	}
+
+	exclusiveLock.RLock()
+
+	return exclusiveLock.RUnlock
 }
 
 // Call executes the specified command name if exists
 func Call(name string, ctx *Context) {
+	cmd, exists := Lookup(name)
+	if !exists {
+		ctx.Conn.WriteError(fmt.Sprintf("Err unknown command %s", strings.ToLower(name)))
+		return
+	}
+
+	if !cmd.AcceptsArgc(ctx.Argc) {
+		ctx.Conn.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", cmd.Name))
+		return
+	}
+
+	if cmd.IsWrite(ctx.Argv) {
+		if replication.IsReplica() {
+			ctx.Conn.WriteError("READONLY You can't write against a read only replica.")
+			return
+		}
+
+		if ctx.Cfg != nil && ctx.Cfg.Server.Redis.ReadOnly {
+			ctx.Conn.WriteError("READONLY You can't write against a read only server.")
+			return
+		}
+	}
+
+	MonitorFeed(cmd.Name, ctx)
+
+	cmd.Handler(ctx)
+}
+
+// Lookup fetches the command with the specified name if exists
+func Lookup(name string) (*Command, bool) {
 	commandsMapLock.RLock()
+	defer commandsMapLock.RUnlock()
 
-	name = strings.ToLower(name)
+	cmd, exists := commandsMap[strings.ToLower(name)]
 
-	cmd, exists := commandsMap[name]
-	if !exists {
-		commandsMapLock.RUnlock()
-		ctx.Conn.WriteError(fmt.Sprintf("Err unknown command %s", name))
-		return
+	return cmd, exists
+}
+
+// Exists whether the specified command name exists or not
+func Exists(name string) bool {
+	_, exists := Lookup(name)
+
+	return exists
+}
+
+// Commands returns all the registered commands sorted by their names
+func Commands() []*Command {
+	commandsMapLock.RLock()
+	defer commandsMapLock.RUnlock()
+
+	result := make([]*Command, 0, len(commandsMap))
+	for _, cmd := range commandsMap {
+		result = append(result, cmd)
 	}
 
-	commandsMapLock.RUnlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// IsWrite whether the specified command (along with its arguments) modifies the data or not
+func IsWrite(name string, argv [][]byte) bool {
+	cmd, exists := Lookup(name)
+
+	return exists && cmd.IsWrite(argv)
+}
+
+// HasFlag whether the command has the specified flag or not
+func (cmd *Command) HasFlag(flag string) bool {
+	for _, f := range cmd.Flags {
+		if f == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsWrite whether calling the command with the specified arguments modifies the data or not
+func (cmd *Command) IsWrite(argv [][]byte) bool {
+	if cmd.HasFlag(FlagWrite) {
+		return true
+	}
+
+	return cmd.WriteIf != nil && cmd.WriteIf(argv)
+}
+
+// touchesData whether the command reads or writes the data or not
+func (cmd *Command) touchesData() bool {
+	return cmd.HasFlag(FlagWrite) || cmd.HasFlag(FlagReadOnly) || cmd.WriteIf != nil
+}
+
+// AcceptsArgc whether the command accepts the specified number of arguments (excluding the command name) or not
+func (cmd *Command) AcceptsArgc(argc int) bool {
+	if cmd.Arity < 0 {
+		return argc+1 >= -cmd.Arity
+	}
+
+	return argc+1 == cmd.Arity
+}
+
+// Keys extracts the keys from the specified arguments (excluding the command name)
+func (cmd *Command) Keys(argv [][]byte) [][]byte {
+	if cmd.FirstKey < 1 || cmd.KeyStep < 1 {
+		return nil
+	}
+
+	last := cmd.LastKey
+	if last < 0 {
+		last = len(argv) + 1 + last
+	}
+
+	var keys [][]byte
+
+	for i := cmd.FirstKey; i <= last && i <= len(argv); i += cmd.KeyStep {
+		keys = append(keys, argv[i-1])
+	}
 
-	cmd(ctx)
+	return keys
 }
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/alash3al/redix/internals/replication"
+)
+
+func init() {
+	// REPLICAOF <host> <port>
+	// REPLICAOF NO ONE
+	Register(&Command{
+		Name:       "replicaof",
+		Arity:      3,
+		Flags:      []string{FlagAdmin, FlagNoScript, FlagStale},
+		Categories: []string{"@admin", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "Makes the server a replica of another one, or turns it back into a primary",
+		Arguments: []*Argument{
+			{Name: "host", Type: ArgString},
+			{Name: "port", Type: ArgString},
+		},
+		Handler: func(c *Context) {
+			if strings.ToLower(string(c.Argv[0])) == "no" && strings.ToLower(string(c.Argv[1])) == "one" {
+				replication.ReplicaOf("", c.Engine)
+				c.Conn.WriteString("OK")
+				return
+			}
+
+			if _, err := strconv.Atoi(string(c.Argv[1])); err != nil {
+				c.Conn.WriteError("ERR Invalid master port")
+				return
+			}
+
+			// the pending async writes would be overwritten by the primary data anyway
+			c.Queue.Drain()
+
+			replication.ReplicaOf(net.JoinHostPort(string(c.Argv[0]), string(c.Argv[1])), c.Engine)
+
+			c.Conn.WriteString("OK")
+		},
+	})
+
+	// SLAVEOF <host> <port>
+	// SLAVEOF NO ONE
+	Register(&Command{
+		Name:       "slaveof",
+		Arity:      3,
+		Flags:      []string{FlagAdmin, FlagNoScript, FlagStale},
+		Categories: []string{"@admin", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "An alias of REPLICAOF",
+		Arguments: []*Argument{
+			{Name: "host", Type: ArgString},
+			{Name: "port", Type: ArgString},
+		},
+		Handler: func(c *Context) {
+			Call("replicaof", c)
+		},
+	})
+
+	// ROLE
+	Register(&Command{
+		Name:       "role",
+		Arity:      1,
+		Flags:      []string{FlagNoScript, FlagLoading, FlagStale, FlagFast},
+		Categories: []string{"@admin", "@fast", "@dangerous"},
+		Group:      "server",
+		Summary:    "Returns the replication role of the server",
+		Handler: func(c *Context) {
+			if replica, ok := replication.CurrentReplica(); ok {
+				host, port, _ := net.SplitHostPort(replica.Addr)
+				portNumber, _ := strconv.Atoi(port)
+				state, offset := replica.State()
+
+				c.Conn.WriteArray(5)
+				c.Conn.WriteBulkString("slave")
+				c.Conn.WriteBulkString(host)
+				c.Conn.WriteInt(portNumber)
+				c.Conn.WriteBulkString(state)
+				c.Conn.WriteInt64(offset)
+				return
+			}
+
+			replicas := replication.Replicas()
+
+			c.Conn.WriteArray(3)
+			c.Conn.WriteBulkString("master")
+			c.Conn.WriteInt64(replication.Offset())
+			c.Conn.WriteArray(len(replicas))
+			for _, replica := range replicas {
+				host, port, _ := net.SplitHostPort(replica.Addr)
+
+				c.Conn.WriteArray(3)
+				c.Conn.WriteBulkString(host)
+				c.Conn.WriteBulkString(port)
+				c.Conn.WriteBulkString(strconv.FormatInt(replica.Offset(), 10))
+			}
+		},
+	})
+
+	// PSYNC <replication id> <offset>
+	// used by the replicas to receive the write log
+	Register(&Command{
+		Name:       "psync",
+		Arity:      3,
+		Flags:      []string{FlagAdmin, FlagNoScript},
+		Categories: []string{"@admin", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "An internal command used by the replicas to stream the write log",
+		Arguments: []*Argument{
+			{Name: "replicationid", Type: ArgString},
+			{Name: "offset", Type: ArgInteger},
+		},
+		Handler: func(c *Context) {
+			offset, err := strconv.ParseInt(string(c.Argv[1]), 10, 64)
+			if err != nil {
+				c.Conn.WriteError("ERR value is not an integer or out of range")
+				return
+			}
+
+			addr := c.Conn.RemoteAddr()
+
+			conn := c.Conn.Detach()
+			defer conn.Close()
+
+			if err := replication.Serve(conn, addr, string(c.Argv[0]), offset); err != nil {
+				log.Println("[ERROR] the replication stream of", addr, "has been interrupted due to:", err.Error())
+			}
+		},
+	})
+}
@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/scripting"
+	"github.com/tidwall/redcon"
+)
+
+func init() {
+	evalArguments := []*Argument{
+		{Name: "script", Type: ArgString},
+		{Name: "numkeys", Type: ArgInteger},
+		{Name: "key", Type: ArgKey, Optional: true, Multiple: true},
+		{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+	}
+
+	evalshaArguments := []*Argument{
+		{Name: "sha1", Type: ArgString},
+		{Name: "numkeys", Type: ArgInteger},
+		{Name: "key", Type: ArgKey, Optional: true, Multiple: true},
+		{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+	}
+
+	// EVAL <script> <numkeys> [<key> ...] [<arg> ...]
+	Register(&Command{
+		Name:       "eval",
+		Arity:      -3,
+		Flags:      []string{FlagNoScript, FlagStale},
+		Categories: []string{"@slow", "@scripting"},
+		Group:      "scripting",
+		Summary:    "Executes a server-side Lua script",
+		Arguments:  evalArguments,
+		Exclusive:  true,
+		Handler: func(c *Context) {
+			eval(c, false)
+		},
+	})
+
+	// EVAL_RO <script> <numkeys> [<key> ...] [<arg> ...]
+	Register(&Command{
+		Name:       "eval_ro",
+		Arity:      -3,
+		Flags:      []string{FlagNoScript, FlagStale},
+		Categories: []string{"@slow", "@scripting"},
+		Group:      "scripting",
+		Summary:    "Executes a read-only server-side Lua script",
+		Arguments:  evalArguments,
+		Exclusive:  true,
+		Handler: func(c *Context) {
+			eval(c, true)
+		},
+	})
+
+	// EVALSHA <sha1> <numkeys> [<key> ...] [<arg> ...]
+	Register(&Command{
+		Name:       "evalsha",
+		Arity:      -3,
+		Flags:      []string{FlagNoScript, FlagStale},
+		Categories: []string{"@slow", "@scripting"},
+		Group:      "scripting",
+		Summary:    "Executes a server-side Lua script by its SHA1 digest",
+		Arguments:  evalshaArguments,
+		Exclusive:  true,
+		Handler: func(c *Context) {
+			evalsha(c, false)
+		},
+	})
+
+	// EVALSHA_RO <sha1> <numkeys> [<key> ...] [<arg> ...]
+	Register(&Command{
+		Name:       "evalsha_ro",
+		Arity:      -3,
+		Flags:      []string{FlagNoScript, FlagStale},
+		Categories: []string{"@slow", "@scripting"},
+		Group:      "scripting",
+		Summary:    "Executes a read-only server-side Lua script by its SHA1 digest",
+		Arguments:  evalshaArguments,
+		Exclusive:  true,
+		Handler: func(c *Context) {
+			evalsha(c, true)
+		},
+	})
+
+	// SCRIPT LOAD <script> | EXISTS <sha1> [<sha1> ...] | FLUSH [ASYNC | SYNC] | KILL
+	Register(&Command{
+		Name:       "script",
+		Arity:      -2,
+		Flags:      []string{FlagNoScript},
+		Categories: []string{"@slow", "@scripting"},
+		Group:      "scripting",
+		Summary:    "Manages the server-side Lua scripts cache",
+		Arguments: []*Argument{
+			{Name: "subcommand", Type: ArgString},
+			{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+		},
+		Handler: func(c *Context) {
+			subcommand := strings.ToLower(string(c.Argv[0]))
+
+			switch subcommand {
+			case "load":
+				if c.Argc != 2 {
+					c.Conn.WriteError("ERR wrong number of arguments for 'script|load' command")
+					return
+				}
+
+				script, err := scripting.Load(c.Argv[1])
+				if err != nil {
+					c.Conn.WriteError("ERR " + err.Error())
+					return
+				}
+
+				c.Conn.WriteBulkString(script.SHA)
+			case "exists":
+				if c.Argc < 2 {
+					c.Conn.WriteError("ERR wrong number of arguments for 'script|exists' command")
+					return
+				}
+
+				c.Conn.WriteArray(c.Argc - 1)
+				for _, sha := range c.Argv[1:] {
+					if _, exists := scripting.Lookup(string(sha)); exists {
+						c.Conn.WriteInt(1)
+					} else {
+						c.Conn.WriteInt(0)
+					}
+				}
+			case "flush":
+				scripting.Flush()
+				c.Conn.WriteString("OK")
+			case "kill":
+				if err := scripting.Kill(); err != nil {
+					c.Conn.WriteError(err.Error())
+					return
+				}
+
+				c.Conn.WriteString("OK")
+			default:
+				c.Conn.WriteError("ERR unknown subcommand '" + subcommand + "'")
+			}
+		},
+	})
+}
+
+// eval compiles (or fetches from the cache) the script in the first argument then runs it
+func eval(c *Context, readOnly bool) {
+	script, err := scripting.Load(c.Argv[0])
+	if err != nil {
+		c.Conn.WriteError("ERR " + err.Error())
+		return
+	}
+
+	runScript(c, readOnly, func(opts *scripting.Options) (interface{}, error) {
+		return scripting.Run(script, opts)
+	})
+}
+
+// evalsha runs the cached script having the SHA1 digest specified in the first argument
+func evalsha(c *Context, readOnly bool) {
+	script, exists := scripting.Lookup(string(c.Argv[0]))
+	if !exists {
+		c.Conn.WriteError("NOSCRIPT No matching script. Please use EVAL.")
+		return
+	}
+
+	runScript(c, readOnly, func(opts *scripting.Options) (interface{}, error) {
+		return scripting.Run(script, opts)
+	})
+}
+
+// runScript runs a script (or a function) using run and the numkeys, keys and args arguments following the script one,
+// the script runs within a transaction if the engine supports them, so it is discarded if the script fails.
+func runScript(c *Context, readOnly bool, run func(*scripting.Options) (interface{}, error)) {
+	numkeys, err := strconv.Atoi(string(c.Argv[1]))
+	if err != nil {
+		c.Conn.WriteError("ERR value is not an integer or out of range")
+		return
+	}
+
+	if numkeys < 0 {
+		c.Conn.WriteError("ERR Number of keys can't be negative")
+		return
+	}
+
+	if numkeys > c.Argc-2 {
+		c.Conn.WriteError("ERR Number of keys can't be greater than number of args")
+		return
+	}
+
+	engine, tx, err := beginScript(c.Engine)
+	if err != nil {
+		c.Conn.WriteError("ERR " + err.Error())
+		return
+	}
+
+	reply, err := run(&scripting.Options{
+		Keys:      c.Argv[2 : 2+numkeys],
+		Args:      c.Argv[2+numkeys:],
+		Call:      scriptCaller(c, engine, readOnly),
+		IsWrite:   isWriteArgs,
+		TimeLimit: time.Duration(c.Cfg.ScriptingConfig().TimeLimit) * time.Millisecond,
+		Killable:  tx != nil,
+	})
+
+	if tx != nil {
+		if err != nil {
+			tx.Rollback()
+		} else if err := tx.Commit(); err == contract.ErrTransactionConflict {
+			c.Conn.WriteError("TRYAGAIN the script conflicted with a concurrent write, retry it")
+			return
+		} else if err != nil {
+			c.Conn.WriteError("ERR " + err.Error())
+			return
+		}
+	}
+
+	if err != nil {
+		c.Conn.WriteError(err.Error())
+		return
+	}
+
+	writeReply(c.Conn, reply)
+}
+
+// beginScript starts a transaction the script writes are applied within if the engine supports that,
+// otherwise the engine itself is used.
+func beginScript(engine contract.Engine) (contract.Engine, contract.Transaction, error) {
+	transactor, ok := engine.(contract.Transactor)
+	if !ok {
+		return engine, nil, nil
+	}
+
+	tx, err := transactor.Begin()
+	if err == contract.ErrTransactionUnsupported {
+		return engine, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tx, tx, nil
+}
+
+// scriptCaller returns a scripting.Caller executing the commands called by a script using the specified engine,
+// the commands see the database selected by the caller but can't change it.
+func scriptCaller(c *Context, engine contract.Engine, readOnly bool) scripting.Caller {
+	// the scripts must see their own writes, so they never write asynchronously
+	cfg := *c.Cfg
+	cfg.Server.Redis.AsyncWrites = false
+
+	session := map[string]interface{}{}
+	for k, v := range c.Session() {
+		session[k] = v
+	}
+
+	session["protocol"] = RESP2
+
+	if client, ok := session["client"].(*Client); ok {
+		session["client"] = client.scriptClient()
+	}
+
+	return func(args [][]byte) interface{} {
+		name := strings.ToLower(string(args[0]))
+
+		cmd, exists := Lookup(name)
+
+		switch {
+		case !exists:
+			return scripting.ErrorReply("ERR Unknown Redis command called from script")
+		case cmd.HasFlag(FlagNoScript):
+			return scripting.ErrorReply("ERR This Redis command is not allowed from script")
+		case readOnly && cmd.IsWrite(args[1:]):
+			return scripting.ErrorReply("ERR Write commands are not allowed from read-only scripts.")
+		}
+
+		rec := &recorder{session: session}
+
+		Call(name, &Context{
+			Conn:   rec,
+			Engine: engine,
+			Queue:  c.Queue,
+			Cfg:    &cfg,
+			Argc:   len(args) - 1,
+			Argv:   args[1:],
+		})
+
+		return rec.reply()
+	}
+}
+
+// isWriteArgs whether the specified command arguments (including the command name) modify the data or not
+func isWriteArgs(args [][]byte) bool {
+	return IsWrite(string(args[0]), args[1:])
+}
+
+// writeReply writes the specified script reply
+func writeReply(conn redcon.Conn, reply interface{}) {
+	switch reply := reply.(type) {
+	case int64:
+		conn.WriteInt64(reply)
+	case []byte:
+		conn.WriteBulk(reply)
+	case scripting.Status:
+		conn.WriteString(string(reply))
+	case scripting.ErrorReply:
+		conn.WriteError(string(reply))
+	case []interface{}:
+		conn.WriteArray(len(reply))
+		for _, item := range reply {
+			writeReply(conn, item)
+		}
+	default:
+		conn.WriteNull()
+	}
+}
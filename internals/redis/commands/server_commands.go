@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/replication"
+)
+
+// Global consts
+const (
+	Version = "5.0.0"
+)
+
+func init() {
+	// HELLO [protover [AUTH username password] [SETNAME clientname]]
+	Register(&Command{
+		Name:       "hello",
+		Arity:      -1,
+		Flags:      []string{FlagNoScript, FlagLoading, FlagStale, FlagFast},
+		Categories: []string{"@fast", "@connection"},
+		Group:      "connection",
+		Summary:    "Handshakes with the server",
+		Arguments: []*Argument{
+			{Name: "arguments", Type: ArgBlock, Optional: true, Arguments: []*Argument{
+				{Name: "protover", Type: ArgInteger},
+				{Name: "clientname", Type: ArgString, Token: "SETNAME", Optional: true},
+			}},
+		},
+		Handler: func(c *Context) {
+			proto := c.Protocol()
+
+			if c.Argc > 0 {
+				ver, err := strconv.Atoi(string(c.Argv[0]))
+				if err != nil {
+					c.Conn.WriteError("ERR Protocol version is not an integer or out of range")
+					return
+				}
+
+				if ver != RESP2 && ver != RESP3 {
+					c.Conn.WriteError("NOPROTO unsupported protocol version")
+					return
+				}
+
+				proto = ver
+			}
+
+			var name []byte
+
+			for i := 1; i < c.Argc; i++ {
+				switch strings.ToLower(string(c.Argv[i])) {
+				case "auth":
+					c.Conn.WriteError("ERR AUTH called without any password configured")
+					return
+				case "setname":
+					if i+1 >= c.Argc {
+						c.Conn.WriteError("ERR syntax error")
+						return
+					}
+					i++
+					name = c.Argv[i]
+				default:
+					c.Conn.WriteError("ERR syntax error")
+					return
+				}
+			}
+
+			c.SessionSet("protocol", proto)
+
+			if name != nil {
+				c.Client().SetName(string(name))
+			}
+
+			role := "master"
+			if replication.IsReplica() {
+				role = "replica"
+			}
+
+			w := c.Writer()
+
+			w.WriteMap(7)
+			w.WriteBulkString("server")
+			w.WriteBulkString("redix")
+			w.WriteBulkString("version")
+			w.WriteBulkString(Version)
+			w.WriteBulkString("proto")
+			w.WriteInt(proto)
+			w.WriteBulkString("id")
+			w.WriteInt64(c.Client().ID)
+			w.WriteBulkString("mode")
+			w.WriteBulkString("standalone")
+			w.WriteBulkString("role")
+			w.WriteBulkString(role)
+			w.WriteBulkString("modules")
+			w.WriteArray(0)
+		},
+	})
+
+	// REBALANCE
+	// moves the keys stored in the wrong shards (i.e after adding a shard) to the shards they belong to
+	Register(&Command{
+		Name:       "rebalance",
+		Arity:      1,
+		Flags:      []string{FlagWrite, FlagAdmin, FlagNoScript},
+		Categories: []string{"@write", "@admin", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "Moves the keys stored in the wrong shards to the shards they belong to",
+		Handler: func(c *Context) {
+			rebalancer, ok := c.Engine.(contract.Rebalancer)
+			if !ok {
+				c.Conn.WriteError("ERR the engine doesn't support rebalancing")
+				return
+			}
+
+			moved, err := rebalancer.Rebalance()
+			if err != nil {
+				c.Conn.WriteError("ERR " + err.Error())
+				return
+			}
+
+			c.Conn.WriteInt64(moved)
+		},
+	})
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alash3al/redix/internals/config"
+)
+
+// Global consts
+const (
+	slowlogMaxArgc   = 32
+	slowlogMaxArgLen = 128
+)
+
+// SlowlogEntry represents a slow log entry
+type SlowlogEntry struct {
+	ID         int64
+	Time       time.Time
+	Duration   time.Duration
+	Argv       []string
+	ClientAddr string
+	ClientName string
+}
+
+var (
+	slowlogEntries []*SlowlogEntry
+	slowlogIDSeq   int64
+	slowlogMaxLen  = config.DefaultSlowlogMaxLen
+	slowlogLock    = &sync.RWMutex{}
+
+	// slowlogLogSlowerThan is accessed atomically, so the commands faster than it don't contend on the lock
+	slowlogLogSlowerThan int64 = config.DefaultSlowlogLogSlowerThan
+)
+
+// ConfigureSlowlog sets the slow log threshold (in microseconds) and its maximum length,
+// a negative threshold disables the slow log while a zero one logs every command.
+func ConfigureSlowlog(logSlowerThan int64, maxLen int) {
+	slowlogLock.Lock()
+	defer slowlogLock.Unlock()
+
+	atomic.StoreInt64(&slowlogLogSlowerThan, logSlowerThan)
+	slowlogMaxLen = maxLen
+
+	if len(slowlogEntries) > maxLen {
+		slowlogEntries = slowlogEntries[:maxLen]
+	}
+}
+
+// SlowlogRecord adds the specified command to the slow log if it took longer than the threshold
+func SlowlogRecord(client *Client, argv [][]byte, d time.Duration) {
+	if logSlowerThan := atomic.LoadInt64(&slowlogLogSlowerThan); logSlowerThan < 0 || d.Microseconds() < logSlowerThan {
+		return
+	}
+
+	slowlogLock.Lock()
+	defer slowlogLock.Unlock()
+
+	if slowlogMaxLen < 1 {
+		return
+	}
+
+	slowlogIDSeq++
+
+	entry := &SlowlogEntry{
+		ID:         slowlogIDSeq,
+		Time:       time.Now(),
+		Duration:   d,
+		Argv:       slowlogTruncateArgv(argv),
+		ClientAddr: client.Addr,
+		ClientName: client.Name(),
+	}
+
+	// the newest entries are kept first
+	slowlogEntries = append([]*SlowlogEntry{entry}, slowlogEntries...)
+
+	if len(slowlogEntries) > slowlogMaxLen {
+		slowlogEntries = slowlogEntries[:slowlogMaxLen]
+	}
+}
+
+// slowlogTruncateArgv converts the specified argv into strings while limiting its size
+func slowlogTruncateArgv(argv [][]byte) []string {
+	argc := len(argv)
+	if argc > slowlogMaxArgc {
+		argc = slowlogMaxArgc - 1
+	}
+
+	result := make([]string, 0, argc+1)
+
+	for _, arg := range argv[:argc] {
+		if len(arg) > slowlogMaxArgLen {
+			result = append(result, fmt.Sprintf("%s... (%d more bytes)", arg[:slowlogMaxArgLen], len(arg)-slowlogMaxArgLen))
+			continue
+		}
+
+		result = append(result, string(arg))
+	}
+
+	if argc < len(argv) {
+		result = append(result, fmt.Sprintf("... (%d more arguments)", len(argv)-argc))
+	}
+
+	return result
+}
+
+func init() {
+	// SLOWLOG GET [count] | LEN | RESET
+	Register(&Command{
+		Name:       "slowlog",
+		Arity:      -2,
+		Flags:      []string{FlagAdmin, FlagLoading, FlagStale},
+		Categories: []string{"@admin", "@slow", "@dangerous"},
+		Group:      "server",
+		Summary:    "Inspects and resets the slow commands log",
+		Arguments: []*Argument{
+			{Name: "subcommand", Type: ArgString},
+			{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+		},
+		Handler: func(c *Context) {
+			subcommand := strings.ToLower(string(c.Argv[0]))
+
+			switch subcommand {
+			case "len":
+				slowlogLock.RLock()
+				c.Conn.WriteInt(len(slowlogEntries))
+				slowlogLock.RUnlock()
+			case "reset":
+				slowlogLock.Lock()
+				slowlogEntries = nil
+				slowlogLock.Unlock()
+
+				c.Conn.WriteString("OK")
+			case "get":
+				count := 10
+
+				if c.Argc > 1 {
+					n, err := strconv.Atoi(string(c.Argv[1]))
+					if err != nil || n < -1 {
+						c.Conn.WriteError("ERR count should be greater than or equal to -1")
+						return
+					}
+					count = n
+				}
+
+				slowlogLock.RLock()
+				defer slowlogLock.RUnlock()
+
+				if count == -1 || count > len(slowlogEntries) {
+					count = len(slowlogEntries)
+				}
+
+				c.Conn.WriteArray(count)
+
+				for _, entry := range slowlogEntries[:count] {
+					c.Conn.WriteArray(6)
+					c.Conn.WriteInt64(entry.ID)
+					c.Conn.WriteInt64(entry.Time.Unix())
+					c.Conn.WriteInt64(entry.Duration.Microseconds())
+					c.Conn.WriteArray(len(entry.Argv))
+					for _, arg := range entry.Argv {
+						c.Conn.WriteBulkString(arg)
+					}
+					c.Conn.WriteBulkString(entry.ClientAddr)
+					c.Conn.WriteBulkString(entry.ClientName)
+				}
+			default:
+				c.Conn.WriteError("ERR unknown subcommand '" + subcommand + "'")
+			}
+		},
+	})
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/tidwall/redcon"
+)
+
+// Global consts
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
+// Writer wraps a redcon.Conn to write the replies using
+// the RESP protocol version negotiated by the client,
+// RESP3 only types are downgraded to their RESP2 equivalents.
+type Writer struct {
+	redcon.Conn
+	Protocol int
+}
+
+// NewWriter creates a new Writer for the specified conn and protocol version
+func NewWriter(conn redcon.Conn, protocol int) *Writer {
+	return &Writer{
+		Conn:     conn,
+		Protocol: protocol,
+	}
+}
+
+// WriteMap writes a map header of the specified number of key-value pairs
+func (w *Writer) WriteMap(count int) {
+	if w.Protocol < RESP3 {
+		w.Conn.WriteArray(count * 2)
+		return
+	}
+
+	w.writeHeader('%', count)
+}
+
+// WriteSet writes a set header of the specified number of elements
+func (w *Writer) WriteSet(count int) {
+	if w.Protocol < RESP3 {
+		w.Conn.WriteArray(count)
+		return
+	}
+
+	w.writeHeader('~', count)
+}
+
+// WritePush writes a push (out of band) header of the specified number of elements
+func (w *Writer) WritePush(count int) {
+	if w.Protocol < RESP3 {
+		w.Conn.WriteArray(count)
+		return
+	}
+
+	w.writeHeader('>', count)
+}
+
+// WriteDouble writes a floating point number
+func (w *Writer) WriteDouble(num float64) {
+	str := strconv.FormatFloat(num, 'f', -1, 64)
+
+	if w.Protocol < RESP3 {
+		w.Conn.WriteBulkString(str)
+		return
+	}
+
+	w.Conn.WriteRaw([]byte("," + str + "\r\n"))
+}
+
+// WriteBool writes a boolean
+func (w *Writer) WriteBool(b bool) {
+	if w.Protocol < RESP3 {
+		if b {
+			w.Conn.WriteInt(1)
+		} else {
+			w.Conn.WriteInt(0)
+		}
+		return
+	}
+
+	if b {
+		w.Conn.WriteRaw([]byte("#t\r\n"))
+	} else {
+		w.Conn.WriteRaw([]byte("#f\r\n"))
+	}
+}
+
+// WriteNull writes a null value
+func (w *Writer) WriteNull() {
+	if w.Protocol < RESP3 {
+		w.Conn.WriteNull()
+		return
+	}
+
+	w.Conn.WriteRaw([]byte("_\r\n"))
+}
+
+// writeHeader writes an aggregate type header
+func (w *Writer) writeHeader(prefix byte, count int) {
+	w.Conn.WriteRaw(append(strconv.AppendInt([]byte{prefix}, int64(count), 10), '\r', '\n'))
+}
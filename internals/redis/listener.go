@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"net"
+	"sync"
+)
+
+// drainListener wraps a net.Listener so it can stop accepting new connections
+// without making the server loop close the already accepted ones.
+type drainListener struct {
+	net.Listener
+
+	stopped   chan struct{}
+	closed    chan struct{}
+	stopOnce  sync.Once
+	closeOnce sync.Once
+}
+
+// newDrainListener wraps the specified listener
+func newDrainListener(ln net.Listener) *drainListener {
+	return &drainListener{
+		Listener: ln,
+		stopped:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Accept waits for the next connection, once the listener is stopped
+// it blocks till the listener is closed.
+func (l *drainListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		select {
+		case <-l.stopped:
+			<-l.closed
+		default:
+		}
+	}
+
+	return conn, err
+}
+
+// Stop stops accepting new connections
+func (l *drainListener) Stop() {
+	l.stopOnce.Do(func() {
+		close(l.stopped)
+		l.Listener.Close()
+	})
+}
+
+// Close stops accepting new connections and releases the listener
+func (l *drainListener) Close() error {
+	l.Stop()
+
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+
+	return nil
+}
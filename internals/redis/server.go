@@ -1,54 +1,303 @@
 package redis
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
-	"sync/atomic"
+	"net"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/alash3al/redix/internals/config"
 	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/metrics"
 	"github.com/alash3al/redix/internals/redis/commands"
+	"github.com/alash3al/redix/internals/stats"
+	"github.com/alash3al/redix/internals/writebehind"
 	"github.com/tidwall/redcon"
 )
 
-var (
-	connCounter int64 = 0
-)
+// Server represents a redis interface server
+type Server struct {
+	cfg       *config.Config
+	engine    contract.Engine
+	queue     *writebehind.Queue
+	listeners []*drainListener
+	servers   []*redcon.Server
+
+	inflight     sync.WaitGroup
+	shuttingDown bool
+	sync.RWMutex
+}
+
+// NewServer creates a new redis server that serves the specified engine,
+// the async writes are applied to the engine through the specified write-behind queue.
+func NewServer(cfg *config.Config, engine contract.Engine, queue *writebehind.Queue) *Server {
+	return &Server{
+		cfg:    cfg,
+		engine: engine,
+		queue:  queue,
+	}
+}
 
 // ListenAndServe start a redis server
-func ListenAndServe(cfg *config.Config, engine contract.Engine) error {
+func (s *Server) ListenAndServe() error {
 	commands.HandleFunc("CLIENTCOUNT", func(c *commands.Context) {
-		c.Conn.WriteAny(atomic.LoadInt64(&connCounter))
+		c.Conn.WriteAny(metrics.Connections.Value())
+	})
+
+	listeners, err := listen(s.cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, ln := range listeners {
+		s.listeners = append(s.listeners, newDrainListener(ln))
+		s.servers = append(s.servers, redcon.NewServerNetwork(ln.Addr().Network(), ln.Addr().String(), s.handle, s.accept, s.closed))
+	}
+
+	// apply the runtime mutable configs whenever they change
+	config.OnChange(func(cfg *config.Config) {
+		slowlogCfg := cfg.SlowlogConfig()
+		commands.ConfigureSlowlog(*slowlogCfg.LogSlowerThan, slowlogCfg.MaxLen)
+
+		for _, srv := range s.servers {
+			srv.SetIdleClose(time.Duration(cfg.Server.Redis.Timeout) * time.Second)
+		}
 	})
 
-	fmt.Println("=> started listening on", cfg.Server.Redis.ListenAddr, "...")
-	return redcon.ListenAndServe(cfg.Server.Redis.ListenAddr,
-		func(conn redcon.Conn, cmd redcon.Command) {
-			ctx := commands.Context{
-				Conn:   conn,
-				Engine: engine,
-				Cfg:    cfg,
-				Argc:   len(cmd.Args) - 1,
-				Argv:   cmd.Args[1:],
-			}
-
-			commands.Call(string(cmd.Args[0]), &ctx)
-		},
-		func(conn redcon.Conn) bool {
-			if cfg.Server.Redis.MaxConns > 0 && cfg.Server.Redis.MaxConns <= atomic.LoadInt64(&connCounter) {
-				log.Println("max connections reached!")
-				return false
-			}
-
-			atomic.AddInt64(&connCounter, 1)
-
-			conn.SetContext(map[string]interface{}{
-				"namespace": "/0/",
-			})
-			return true
-		},
-		func(conn redcon.Conn, err error) {
-			atomic.AddInt64(&connCounter, -1)
-		},
-	)
+	config.Store(s.cfg)
+
+	errChan := make(chan error, len(s.listeners))
+
+	for i, ln := range s.listeners {
+		go (func(srv *redcon.Server, ln net.Listener) {
+			errChan <- srv.Serve(ln)
+		})(s.servers[i], ln)
+	}
+
+	return <-errChan
+}
+
+// Shutdown gracefully shuts down the server, it stops accepting new connections,
+// waits for the in-flight commands to finish then disconnects all the clients,
+// the context deadline bounds the whole process.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.Lock()
+	s.shuttingDown = true
+	s.Unlock()
+
+	for _, ln := range s.listeners {
+		ln.Stop()
+	}
+
+	err := wait(ctx, s.inflight.Wait)
+
+	// the long living clients (subscribers, monitors, ...) are disconnected too
+	for _, client := range commands.Clients() {
+		client.Kill()
+	}
+
+	for _, srv := range s.servers {
+		srv.Close()
+	}
+
+	return err
+}
+
+// handle handles the incoming commands
+func (s *Server) handle(conn redcon.Conn, cmd redcon.Command) {
+	s.RLock()
+	if s.shuttingDown {
+		s.RUnlock()
+		conn.WriteError("ERR the server is shutting down")
+		conn.Close()
+		return
+	}
+	s.inflight.Add(1)
+	s.RUnlock()
+
+	sconn := &serverConn{Conn: conn, inflight: &s.inflight}
+	defer sconn.done()
+
+	ctx := commands.Context{
+		Conn:   sconn,
+		Engine: s.engine,
+		Queue:  s.queue,
+		Cfg:    config.Current(),
+		Argc:   len(cmd.Args) - 1,
+		Argv:   cmd.Args[1:],
+	}
+
+	name := strings.ToLower(string(cmd.Args[0]))
+
+	// a paused server must still accept the client command, so it can be unpaused
+	if name != "client" {
+		commands.WaitIfPaused(commands.IsWrite(name, ctx.Argv))
+	}
+
+	client := ctx.Client()
+	client.Touch(name)
+
+	if !commands.Exists(name) {
+		commands.Execute(name, &ctx)
+		return
+	}
+
+	start := time.Now()
+
+	commands.Execute(name, &ctx)
+
+	elapsed := time.Since(start)
+
+	stats.CommandProcessed(name, elapsed, sconn.failed)
+	metrics.ObserveCommand(name, elapsed)
+	commands.SlowlogRecord(client, cmd.Args, elapsed)
+}
+
+// accept accepts the incoming connections
+func (s *Server) accept(conn redcon.Conn) bool {
+	maxConns := config.Current().Server.Redis.MaxConns
+
+	if maxConns > 0 && maxConns <= metrics.Connections.Value() {
+		log.Println("max connections reached!")
+		return false
+	}
+
+	metrics.Connections.Inc()
+	stats.ConnectionReceived()
+
+	conn.SetContext(map[string]interface{}{
+		"client":    commands.RegisterClient(conn),
+		"namespace": "/0/",
+		"protocol":  commands.RESP2,
+	})
+	return true
+}
+
+// closed cleans up after the closed connections
+func (s *Server) closed(conn redcon.Conn, err error) {
+	metrics.Connections.Dec()
+
+	if session, ok := conn.Context().(map[string]interface{}); ok {
+		commands.UnregisterClient(session["client"].(*commands.Client))
+	}
+}
+
+// wait calls fn and waits for it to return or the context to be done
+func wait(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+
+	go (func() {
+		fn()
+		close(done)
+	})()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// listen opens all the listeners declared in the redis server configs
+func listen(cfg *config.Config) ([]net.Listener, error) {
+	var listeners []net.Listener
+
+	plainAddr := cfg.Server.Redis.ListenAddr
+
+	if tlsCfg := cfg.Server.Redis.TLS; tlsCfg != nil {
+		tlsConfig, err := NewTLSConfig(tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsAddr := tlsCfg.ListenAddr
+
+		// if no dedicated tls address specified, then the main address is tls only
+		if tlsAddr == "" {
+			tlsAddr, plainAddr = plainAddr, ""
+		}
+
+		ln, err := tls.Listen("tcp", tlsAddr, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Println("=> started listening (tls) on", tlsAddr, "...")
+
+		listeners = append(listeners, ln)
+	}
+
+	if plainAddr != "" {
+		ln, err := net.Listen("tcp", plainAddr)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+
+		fmt.Println("=> started listening on", plainAddr, "...")
+
+		listeners = append(listeners, ln)
+	}
+
+	if socketPath := cfg.Server.Redis.UnixSocket; socketPath != "" {
+		ln, err := listenUnix(socketPath, cfg.Server.Redis.UnixSocketPerms)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+
+		fmt.Println("=> started listening on unix socket", socketPath, "...")
+
+		listeners = append(listeners, ln)
+	}
+
+	if len(listeners) < 1 {
+		return nil, fmt.Errorf("you must specify at least one address to listen on")
+	}
+
+	return listeners, nil
+}
+
+// closeListeners closes the specified listeners
+func closeListeners(listeners []net.Listener) {
+	for _, ln := range listeners {
+		ln.Close()
+	}
+}
+
+// serverConn wraps a redcon.Conn to keep track of the error replies
+// and whether the command it serves is still in-flight or not
+type serverConn struct {
+	redcon.Conn
+	failed   bool
+	detached bool
+	inflight *sync.WaitGroup
+}
+
+// WriteError writes an error reply to the client and records it
+func (c *serverConn) WriteError(msg string) {
+	c.failed = true
+	stats.ErrorReplied()
+	c.Conn.WriteError(msg)
+}
+
+// Detach detaches the connection from the server loop,
+// the command isn't considered in-flight anymore after that.
+func (c *serverConn) Detach() redcon.DetachedConn {
+	c.done()
+	c.detached = true
+
+	return c.Conn.Detach()
+}
+
+// done marks the command as no longer in-flight
+func (c *serverConn) done() {
+	if !c.detached {
+		c.inflight.Done()
+	}
 }
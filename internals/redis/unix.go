@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// listenUnix listens on the specified unix socket path after removing any stale socket
+// file found there, then applies the specified (octal) permissions to the socket file
+func listenUnix(path string, perms string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if perms != "" {
+		mode, err := strconv.ParseUint(perms, 8, 32)
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("invalid unix socket permissions (%s) specified", perms)
+		}
+
+		if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+
+	return ln, nil
+}
+
+// removeStaleSocket removes the socket file at the specified path
+// only if there is no server accepting connections on it
+func removeStaleSocket(path string) error {
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if stat.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("the unix socket path (%s) exists and isn't a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("the unix socket (%s) is already in use", path)
+	}
+
+	return os.Remove(path)
+}
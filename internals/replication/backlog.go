@@ -0,0 +1,151 @@
+package replication
+
+import (
+	"sync"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// Entry represents a write applied to the primary engine
+type Entry struct {
+	// Offset the position of the entry in the write log
+	Offset int64
+
+	// ReplID the id of the write log the entry has been recorded in, an offset is only meaningful along with it
+	ReplID string
+
+	// Delete whether the entry deletes its key (as read-and-delete does) or writes Input
+	Delete bool
+
+	// Input the write input, only its Key is set for the deletes
+	Input *contract.WriteInput
+}
+
+// feed represents a subscription to the entries appended to the backlog
+type feed struct {
+	entries chan *Entry
+
+	// lost is closed once the subscriber couldn't keep up and missed entries
+	lost chan struct{}
+}
+
+// backlog represents a bounded in-memory write log
+type backlog struct {
+	entries []*Entry
+	first   int
+	count   int
+	offset  int64
+	replid  string
+	feeds   map[*feed]struct{}
+
+	sync.Mutex
+}
+
+// newBacklog creates a new backlog keeping up to the specified number of entries
+func newBacklog(size int) *backlog {
+	return &backlog{
+		entries: make([]*Entry, size),
+		replid:  newReplID(),
+		feeds:   map[*feed]struct{}{},
+	}
+}
+
+// append assigns the next offset to the specified entry, appends it then passes it to the subscribers
+func (b *backlog) append(entry *Entry) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.offset++
+	entry.Offset = b.offset
+	entry.ReplID = b.replid
+
+	if b.count < len(b.entries) {
+		b.entries[(b.first+b.count)%len(b.entries)] = entry
+		b.count++
+	} else {
+		b.entries[b.first] = entry
+		b.first = (b.first + 1) % len(b.entries)
+	}
+
+	for f := range b.feeds {
+		select {
+		case f.entries <- entry:
+		default:
+			delete(b.feeds, f)
+			close(f.lost)
+		}
+	}
+}
+
+// currentOffset returns the offset of the last appended entry
+func (b *backlog) currentOffset() int64 {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.offset
+}
+
+// id returns the id of the write log
+func (b *backlog) id() string {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.replid
+}
+
+// renew assigns a new id to the write log, so the offsets recorded so far can't be resumed anymore
+func (b *backlog) renew() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.replid = newReplID()
+}
+
+// subscribe subscribes to the entries appended after the current offset, which is returned
+func (b *backlog) subscribe(buffer int) (*feed, int64) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.newFeed(buffer), b.offset
+}
+
+// subscribeSince returns the entries appended after the specified offset of the write log having the specified id
+// and subscribes to the next ones, it fails if the id isn't the current one or some of these entries aren't in the backlog anymore.
+func (b *backlog) subscribeSince(replid string, offset int64, buffer int) ([]*Entry, *feed, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	oldest := b.offset - int64(b.count) + 1
+
+	if replid != b.replid || offset < oldest-1 || offset > b.offset {
+		return nil, nil, false
+	}
+
+	var entries []*Entry
+
+	for i := offset - oldest + 1; i < int64(b.count); i++ {
+		entries = append(entries, b.entries[(b.first+int(i))%len(b.entries)])
+	}
+
+	return entries, b.newFeed(buffer), true
+}
+
+// unsubscribe cancels the specified subscription
+func (b *backlog) unsubscribe(f *feed) {
+	b.Lock()
+	defer b.Unlock()
+
+	delete(b.feeds, f)
+}
+
+// newFeed registers a new subscription, the lock must be held by the caller
+func (b *backlog) newFeed(buffer int) *feed {
+	f := &feed{
+		entries: make(chan *Entry, buffer),
+		lost:    make(chan struct{}),
+	}
+
+	b.feeds[f] = struct{}{}
+
+	return f
+}
@@ -0,0 +1,251 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// stripesCount the number of the locks the keys are spread over to keep the writes of each key in order
+const stripesCount = 256
+
+// Engine wraps a contract.Engine to record the applied writes in the write log
+type Engine struct {
+	contract.Engine
+
+	backlog *backlog
+	stripes [stripesCount]sync.Mutex
+
+	// snapshot is held exclusively while subscribing a snapshot and by the writes affecting many keys
+	snapshot sync.RWMutex
+}
+
+// the number of the snapshot keys buffered before flushing them to the replica
+const snapshotFlushEvery = 1000
+
+// batchEngine a replicated Engine for the engines that support batch writes
+type batchEngine struct {
+	*Engine
+	batchWriter contract.BatchWriter
+}
+
+// Write writes into the underlying engine then records the write if it succeeded
+func (e *Engine) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	if input == nil {
+		return e.Engine.Write(input)
+	}
+
+	if input.Key == nil || input.Value == nil {
+		e.snapshot.Lock()
+		defer e.snapshot.Unlock()
+	} else {
+		e.snapshot.RLock()
+		defer e.snapshot.RUnlock()
+
+		stripe := &e.stripes[stripeOf(input.Key)]
+		stripe.Lock()
+		defer stripe.Unlock()
+	}
+
+	ret, err := e.Engine.Write(input)
+	if err != nil {
+		return nil, err
+	}
+
+	e.backlog.append(entryOf(e.Engine, input))
+
+	return ret, nil
+}
+
+// Read reads from the underlying engine, the deletions are recorded
+func (e *Engine) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	if input == nil || !input.Delete {
+		return e.Engine.Read(input)
+	}
+
+	e.snapshot.RLock()
+	defer e.snapshot.RUnlock()
+
+	stripe := &e.stripes[stripeOf(input.Key)]
+	stripe.Lock()
+	defer stripe.Unlock()
+
+	ret, err := e.Engine.Read(input)
+	if err != nil {
+		return nil, err
+	}
+
+	e.backlog.append(&Entry{
+		Delete: true,
+		Input:  &contract.WriteInput{Key: append([]byte(nil), input.Key...)},
+	})
+
+	return ret, nil
+}
+
+// Stats reports the stats of the underlying engine if it supports that
+func (e *Engine) Stats() (map[string]interface{}, error) {
+	if reporter, ok := e.Engine.(contract.Reporter); ok {
+		return reporter.Stats()
+	}
+
+	return nil, nil
+}
+
+// Keyspace reports the keyspace of the underlying engine if it supports that
+func (e *Engine) Keyspace() (map[int]*contract.KeyspaceStats, error) {
+	if reporter, ok := e.Engine.(contract.Reporter); ok {
+		return reporter.Keyspace()
+	}
+
+	return nil, nil
+}
+
+// Rebalance rebalances the underlying engine if it supports that
+func (e *Engine) Rebalance() (int64, error) {
+	if rebalancer, ok := e.Engine.(contract.Rebalancer); ok {
+		return rebalancer.Rebalance()
+	}
+
+	return 0, fmt.Errorf("the engine doesn't support rebalancing")
+}
+
+// SubscribeContext subscribes using the underlying engine till the context is done
+func (e *Engine) SubscribeContext(ctx context.Context, channel []byte, cb func([]byte) error) error {
+	return contract.SubscribeContext(ctx, e.Engine, channel, cb)
+}
+
+// PSubscribe subscribes to the channels matching the pattern using the underlying engine
+func (e *Engine) PSubscribe(ctx context.Context, pattern []byte, cb func([]byte, []byte) error) error {
+	return contract.PSubscribe(ctx, e.Engine, pattern, cb)
+}
+
+// Snapshot subscribes to the entries appended after the current write log offset then calls fn with that offset
+// and the underlying engine, the writes are only blocked while subscribing, so fn may see some of the writes
+// after that offset, replaying them on top of the snapshot is safe as they are recorded as the values they resulted in.
+func (e *Engine) Snapshot(buffer int, fn func(offset int64, engine contract.Engine) error) (*feed, error) {
+	e.snapshot.Lock()
+	f, offset := e.backlog.subscribe(buffer)
+	e.snapshot.Unlock()
+
+	if err := fn(offset, e.Engine); err != nil {
+		e.backlog.unsubscribe(f)
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// WriteBatch writes the specified inputs into the underlying engine at once then records them if it succeeded
+func (e *batchEngine) WriteBatch(inputs []*contract.WriteInput) error {
+	exclusive := false
+	stripes := map[int]bool{}
+
+	for _, input := range inputs {
+		if input == nil || input.Key == nil || input.Value == nil {
+			exclusive = true
+			break
+		}
+
+		stripes[stripeOf(input.Key)] = true
+	}
+
+	if exclusive {
+		e.snapshot.Lock()
+		defer e.snapshot.Unlock()
+	} else {
+		e.snapshot.RLock()
+		defer e.snapshot.RUnlock()
+
+		// the stripes are always locked in the same order, so concurrent batches can't deadlock
+		ordered := make([]int, 0, len(stripes))
+		for stripe := range stripes {
+			ordered = append(ordered, stripe)
+		}
+
+		sort.Ints(ordered)
+
+		for _, stripe := range ordered {
+			e.stripes[stripe].Lock()
+			defer e.stripes[stripe].Unlock()
+		}
+	}
+
+	if err := e.batchWriter.WriteBatch(inputs); err != nil {
+		return err
+	}
+
+	for _, input := range inputs {
+		if input != nil {
+			e.backlog.append(entryOf(e.Engine, input))
+		}
+	}
+
+	return nil
+}
+
+// entryOf returns the entry recording the specified write, the writes depending on the current value
+// of their key are recorded as the value they resulted in (read using the specified engine), so replaying
+// an entry twice yields the same value, the lock of the key must be held by the caller.
+func entryOf(engine contract.Engine, input *contract.WriteInput) *Entry {
+	relative := input.Increment || input.Append || input.OnlyIfNotExists || input.KeepTTL
+
+	if input.Key == nil || input.Value == nil || !relative {
+		return &Entry{Input: copyWriteInput(input)}
+	}
+
+	ro, err := engine.Read(&contract.ReadInput{Key: input.Key})
+	if err != nil {
+		// the write is recorded as is, the replicas applying it in order still get the same value
+		log.Println("[ERROR] unable to read the written key", string(input.Key), "due to:", err.Error())
+		return &Entry{Input: copyWriteInput(input)}
+	}
+
+	if !ro.Exists {
+		return &Entry{
+			Delete: true,
+			Input:  &contract.WriteInput{Key: append([]byte(nil), input.Key...)},
+		}
+	}
+
+	entry := &Entry{
+		Input: &contract.WriteInput{
+			Key:   append([]byte(nil), input.Key...),
+			Value: append([]byte{}, ro.Value...),
+		},
+	}
+
+	if ro.TTL > 0 {
+		entry.Input.TTL = ro.TTL
+	}
+
+	return entry
+}
+
+// stripeOf returns the lock index of the specified key
+func stripeOf(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+
+	return int(h.Sum32() % stripesCount)
+}
+
+// copyWriteInput returns a deep copy of the specified input, as the callers may reuse their buffers
+func copyWriteInput(input *contract.WriteInput) *contract.WriteInput {
+	cp := *input
+
+	if input.Key != nil {
+		cp.Key = append([]byte{}, input.Key...)
+	}
+
+	if input.Value != nil {
+		cp.Value = append([]byte{}, input.Value...)
+	}
+
+	return &cp
+}
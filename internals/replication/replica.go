@@ -0,0 +1,349 @@
+package replication
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// the states of a replica
+const (
+	StateConnect   = "connect"
+	StateSync      = "sync"
+	StateConnected = "connected"
+)
+
+// Replica represents the replication state of the current node while being a replica
+type Replica struct {
+	Addr string
+
+	engine  contract.Engine
+	state   string
+	replid  string
+	offset  int64
+	conn    net.Conn
+	stopped bool
+
+	sync.RWMutex
+}
+
+// newReplica creates a new replica of the specified primary address applying the writes to the specified engine
+func newReplica(addr string, engine contract.Engine) *Replica {
+	return &Replica{
+		Addr:   addr,
+		engine: engine,
+		state:  StateConnect,
+		replid: "?",
+		offset: -1,
+	}
+}
+
+// State returns the state of the link with the primary and the offset of the last applied entry
+func (r *Replica) State() (string, int64) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.state, r.offset
+}
+
+// run keeps the replica in sync with its primary till it is stopped
+func (r *Replica) run() {
+	for {
+		err := r.sync()
+
+		r.Lock()
+		stopped := r.stopped
+		r.state = StateConnect
+		r.Unlock()
+
+		if stopped {
+			return
+		}
+
+		log.Println("[ERROR] the replication link with", r.Addr, "has been interrupted due to:", err.Error())
+
+		time.Sleep(time.Second)
+	}
+}
+
+// stop disconnects the replica from its primary
+func (r *Replica) stop() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stopped = true
+
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+// sync connects to the primary then applies its write log
+func (r *Replica) sync() error {
+	conn, err := net.DialTimeout("tcp", r.Addr, time.Second*5)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	r.Lock()
+	if r.stopped {
+		r.Unlock()
+		return nil
+	}
+	r.conn = conn
+	replid, offset := r.replid, r.offset
+	r.Unlock()
+
+	reader := bufio.NewReader(conn)
+
+	if _, err := fmt.Fprintf(conn, "*3\r\n$5\r\nPSYNC\r\n$%d\r\n%s\r\n$%d\r\n%d\r\n", len(replid), replid, len(strconv.FormatInt(offset, 10)), offset); err != nil {
+		return err
+	}
+
+	reply, err := readValue(reader)
+	if err != nil {
+		return err
+	}
+
+	status, ok := reply.(string)
+	if !ok {
+		return fmt.Errorf("unexpected reply to PSYNC (%v)", reply)
+	}
+
+	parts := strings.Fields(status)
+
+	switch {
+	case len(parts) == 3 && parts[0] == "FULLRESYNC":
+		offset, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return err
+		}
+
+		r.setState(StateSync, "", -1)
+
+		if err := r.loadSnapshot(reader); err != nil {
+			return err
+		}
+
+		r.setState(StateConnected, parts[1], offset)
+	case len(parts) == 2 && parts[0] == "CONTINUE":
+		r.setState(StateConnected, parts[1], offset)
+	default:
+		return fmt.Errorf("unexpected reply to PSYNC (%s)", status)
+	}
+
+	fmt.Println("=> synchronized with the primary", r.Addr, "...")
+
+	for {
+		fields, err := readEntry(reader)
+		if err != nil {
+			return err
+		}
+
+		if fields[0] == nil {
+			return fmt.Errorf("invalid entry received")
+		}
+
+		switch string(fields[0]) {
+		case "PING":
+			continue
+		case "W", "D":
+			offset, err := r.apply(fields)
+			if err != nil {
+				return err
+			}
+
+			r.setState(StateConnected, "", offset)
+		default:
+			return fmt.Errorf("unknown entry (%s) received", fields[0])
+		}
+	}
+}
+
+// loadSnapshot replaces the local data by the snapshot sent by the primary
+func (r *Replica) loadSnapshot(reader *bufio.Reader) error {
+	if _, err := r.engine.Write(&contract.WriteInput{Key: nil, Value: nil}); err != nil {
+		return err
+	}
+
+	for {
+		fields, err := readEntry(reader)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(fields) == 1 && string(fields[0]) == "SNAPEND":
+			return nil
+		case len(fields) == 4 && string(fields[0]) == "SNAP":
+			ttl, err := strconv.ParseInt(string(fields[3]), 10, 64)
+			if err != nil {
+				return err
+			}
+
+			if _, err := r.engine.Write(&contract.WriteInput{
+				Key:   fields[1],
+				Value: fields[2],
+				TTL:   time.Duration(ttl) * time.Millisecond,
+			}); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid snapshot entry received")
+		}
+	}
+}
+
+// apply applies the specified write log entry then returns its offset
+func (r *Replica) apply(fields [][]byte) (int64, error) {
+	if (string(fields[0]) == "D" && len(fields) != 3) || (string(fields[0]) == "W" && len(fields) != 6) {
+		return 0, fmt.Errorf("invalid entry received")
+	}
+
+	offset, err := strconv.ParseInt(string(fields[1]), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if string(fields[0]) == "D" {
+		_, err := r.engine.Read(&contract.ReadInput{Key: fields[2], Delete: true})
+		return offset, err
+	}
+
+	flags, err := strconv.Atoi(string(fields[4]))
+	if err != nil {
+		return 0, err
+	}
+
+	ttl, err := strconv.ParseInt(string(fields[5]), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = r.engine.Write(&contract.WriteInput{
+		Key:             fields[2],
+		Value:           fields[3],
+		Increment:       flags&flagIncrement != 0,
+		Append:          flags&flagAppend != 0,
+		OnlyIfNotExists: flags&flagOnlyIfNotExists != 0,
+		KeepTTL:         flags&flagKeepTTL != 0,
+		TTL:             time.Duration(ttl) * time.Millisecond,
+	})
+
+	return offset, err
+}
+
+// setState updates the replica state, the empty replid and the negative offset are ignored
+func (r *Replica) setState(state, replid string, offset int64) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.state = state
+
+	if replid != "" {
+		r.replid = replid
+	}
+
+	if offset >= 0 {
+		r.offset = offset
+	}
+}
+
+// readEntry reads an array of bulk strings, the null bulk strings are returned as nil
+func readEntry(reader *bufio.Reader) ([][]byte, error) {
+	v, err := readValue(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	items, ok := v.([]interface{})
+	if !ok || len(items) < 1 {
+		return nil, fmt.Errorf("invalid entry received")
+	}
+
+	fields := make([][]byte, len(items))
+
+	for i, item := range items {
+		switch item := item.(type) {
+		case []byte:
+			fields[i] = item
+		case int64:
+			fields[i] = []byte(strconv.FormatInt(item, 10))
+		case nil:
+			fields[i] = nil
+		default:
+			return nil, fmt.Errorf("invalid entry received")
+		}
+	}
+
+	return fields, nil
+}
+
+// readValue reads a RESP value, the simple strings are returned as string, the bulk strings as []byte,
+// the integers as int64, the arrays as []interface{} and the errors as error.
+func readValue(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) < 1 {
+		return nil, fmt.Errorf("empty reply received")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("%s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		if size < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+
+		return data[:size], nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		if size < 0 {
+			return nil, nil
+		}
+
+		items := make([]interface{}, size)
+
+		for i := range items {
+			if items[i], err = readValue(reader); err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply (%s) received", line)
+	}
+}
@@ -0,0 +1,319 @@
+// Package replication streams the writes applied to a primary node to its replicas
+package replication
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// Global consts
+const (
+	DefaultBacklogSize = 10000
+
+	// pingInterval how often the primary pings an idle replica, so a dead one is detected
+	pingInterval = time.Second * 10
+)
+
+// the flags of the streamed write entries
+const (
+	flagIncrement = 1 << iota
+	flagAppend
+	flagOnlyIfNotExists
+	flagKeepTTL
+)
+
+// Conn represents a connection a replica stream is written to
+type Conn interface {
+	WriteString(string)
+	WriteBulk([]byte)
+	WriteBulkString(string)
+	WriteInt64(int64)
+	WriteArray(int)
+	WriteNull()
+	Flush() error
+}
+
+// ReplicaInfo represents a replica connected to the current node
+type ReplicaInfo struct {
+	Addr        string
+	ConnectedAt time.Time
+
+	offset int64
+}
+
+// Offset returns the offset of the last entry sent to the replica
+func (r *ReplicaInfo) Offset() int64 {
+	return atomic.LoadInt64(&r.offset)
+}
+
+var (
+	primary  *Engine
+	replica  *Replica
+	replicas = map[*ReplicaInfo]struct{}{}
+	lock     = &sync.RWMutex{}
+)
+
+// Wrap wraps the specified engine with a replicated one keeping the specified number of entries in its backlog,
+// the optional engine interfaces are kept available, it must be called once.
+func Wrap(engine contract.Engine, backlogSize int) contract.Engine {
+	if backlogSize < 1 {
+		backlogSize = DefaultBacklogSize
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	primary = &Engine{
+		Engine:  engine,
+		backlog: newBacklog(backlogSize),
+	}
+
+	if batchWriter, ok := engine.(contract.BatchWriter); ok {
+		return &batchEngine{Engine: primary, batchWriter: batchWriter}
+	}
+
+	return primary
+}
+
+// ReplID returns the id of the write log of the current node, it changes on every start
+// and once the node is turned back into a primary.
+func ReplID() string {
+	return primary.backlog.id()
+}
+
+// Offset returns the offset of the last write recorded by the current node
+func Offset() int64 {
+	return primary.backlog.currentOffset()
+}
+
+// Replicas returns the replicas connected to the current node sorted by their connection time
+func Replicas() []*ReplicaInfo {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	result := make([]*ReplicaInfo, 0, len(replicas))
+	for r := range replicas {
+		result = append(result, r)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ConnectedAt.Before(result[j].ConnectedAt)
+	})
+
+	return result
+}
+
+// ReplicaOf makes the current node a replica of the specified primary address,
+// an empty address turns it back into a primary.
+func ReplicaOf(addr string, engine contract.Engine) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if replica != nil {
+		replica.stop()
+		replica = nil
+	}
+
+	if addr == "" {
+		// the write log continues from here, so the replicas of the current node must resync
+		primary.backlog.renew()
+		return
+	}
+
+	replica = newReplica(addr, engine)
+	go replica.run()
+}
+
+// Stop disconnects the current node from its primary if it is a replica
+func Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if replica != nil {
+		replica.stop()
+		replica = nil
+	}
+}
+
+// CurrentReplica returns the replication state of the current node if it is a replica
+func CurrentReplica() (*Replica, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return replica, replica != nil
+}
+
+// IsReplica whether the current node is a replica or not
+func IsReplica() bool {
+	_, ok := CurrentReplica()
+
+	return ok
+}
+
+// Serve streams the write log to the replica connected using the specified conn, the stream continues
+// from the specified offset if it still in the backlog, otherwise a full snapshot is sent first.
+func Serve(conn Conn, addr string, fromReplID string, fromOffset int64) error {
+	bufferSize := len(primary.backlog.entries)
+
+	info := &ReplicaInfo{Addr: addr, ConnectedAt: time.Now()}
+
+	lock.Lock()
+	replicas[info] = struct{}{}
+	lock.Unlock()
+
+	defer (func() {
+		lock.Lock()
+		delete(replicas, info)
+		lock.Unlock()
+	})()
+
+	var f *feed
+
+	if entries, continued, ok := primary.backlog.subscribeSince(fromReplID, fromOffset, bufferSize); ok {
+		f = continued
+
+		conn.WriteString("CONTINUE " + fromReplID)
+
+		for _, entry := range entries {
+			writeEntry(conn, entry)
+			atomic.StoreInt64(&info.offset, entry.Offset)
+		}
+	} else {
+		currentReplID := primary.backlog.id()
+
+		var err error
+
+		f, err = primary.Snapshot(bufferSize, func(offset int64, engine contract.Engine) error {
+			conn.WriteString(fmt.Sprintf("FULLRESYNC %s %d", currentReplID, offset))
+			atomic.StoreInt64(&info.offset, offset)
+
+			count := 0
+
+			err := engine.Iterate(&contract.IteratorOpts{
+				Prefix: []byte(""),
+				Callback: func(ro *contract.ReadOutput) error {
+					ttl := int64(0)
+					if ro.TTL > 0 {
+						ttl = ro.TTL.Milliseconds()
+					}
+
+					conn.WriteArray(4)
+					conn.WriteBulkString("SNAP")
+					conn.WriteBulk(ro.Key)
+					conn.WriteBulk(ro.Value)
+					conn.WriteInt64(ttl)
+
+					// the snapshot isn't buffered as a whole, as it may not fit in the memory
+					if count++; count%snapshotFlushEvery == 0 {
+						return conn.Flush()
+					}
+
+					return nil
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			conn.WriteArray(1)
+			conn.WriteBulkString("SNAPEND")
+
+			return conn.Flush()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	defer primary.backlog.unsubscribe(f)
+
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry := <-f.entries:
+			writeEntry(conn, entry)
+			atomic.StoreInt64(&info.offset, entry.Offset)
+
+			if len(f.entries) > 0 {
+				continue
+			}
+		case <-f.lost:
+			return fmt.Errorf("the replica couldn't keep up with the writes")
+		case <-ticker.C:
+			conn.WriteArray(1)
+			conn.WriteBulkString("PING")
+		}
+
+		if err := conn.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// writeEntry writes the specified entry to the replica stream
+func writeEntry(conn Conn, entry *Entry) {
+	input := entry.Input
+
+	if entry.Delete {
+		conn.WriteArray(3)
+		conn.WriteBulkString("D")
+		conn.WriteBulkString(strconv.FormatInt(entry.Offset, 10))
+		conn.WriteBulk(input.Key)
+		return
+	}
+
+	flags := 0
+	if input.Increment {
+		flags |= flagIncrement
+	}
+	if input.Append {
+		flags |= flagAppend
+	}
+	if input.OnlyIfNotExists {
+		flags |= flagOnlyIfNotExists
+	}
+	if input.KeepTTL {
+		flags |= flagKeepTTL
+	}
+
+	conn.WriteArray(6)
+	conn.WriteBulkString("W")
+	conn.WriteBulkString(strconv.FormatInt(entry.Offset, 10))
+
+	if input.Key == nil {
+		conn.WriteNull()
+	} else {
+		conn.WriteBulk(input.Key)
+	}
+
+	if input.Value == nil {
+		conn.WriteNull()
+	} else {
+		conn.WriteBulk(input.Value)
+	}
+
+	conn.WriteInt64(int64(flags))
+	conn.WriteInt64(input.TTL.Milliseconds())
+}
+
+// newReplID generates a new random write log id
+func newReplID() string {
+	id := make([]byte, 20)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,86 @@
+package replication
+
+import (
+	"sync"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// transaction wraps a contract.Transaction to record its writes in the write log once committed
+type transaction struct {
+	contract.Transaction
+
+	engine  *Engine
+	entries []*Entry
+	sync.Mutex
+}
+
+// Begin starts a new transaction on the underlying engine if it supports that
+func (e *Engine) Begin() (contract.Transaction, error) {
+	transactor, ok := e.Engine.(contract.Transactor)
+	if !ok {
+		return nil, contract.ErrTransactionUnsupported
+	}
+
+	tx, err := transactor.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{Transaction: tx, engine: e}, nil
+}
+
+// Write writes into the underlying transaction then keeps the write to record it once committed
+func (t *transaction) Write(input *contract.WriteInput) (*contract.WriteOutput, error) {
+	ret, err := t.Transaction.Write(input)
+	if err != nil || input == nil {
+		return ret, err
+	}
+
+	t.keep(entryOf(t.Transaction, input))
+
+	return ret, nil
+}
+
+// Read reads from the underlying transaction, the deletions are kept to record them once committed
+func (t *transaction) Read(input *contract.ReadInput) (*contract.ReadOutput, error) {
+	ret, err := t.Transaction.Read(input)
+	if err != nil || input == nil || !input.Delete {
+		return ret, err
+	}
+
+	t.keep(&Entry{
+		Delete: true,
+		Input:  &contract.WriteInput{Key: append([]byte(nil), input.Key...)},
+	})
+
+	return ret, nil
+}
+
+// Commit commits the underlying transaction then records its writes,
+// the snapshots are blocked meanwhile so none of them sees the writes without their entries.
+func (t *transaction) Commit() error {
+	t.engine.snapshot.Lock()
+	defer t.engine.snapshot.Unlock()
+
+	if err := t.Transaction.Commit(); err != nil {
+		return err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	for _, entry := range t.entries {
+		t.engine.backlog.append(entry)
+	}
+
+	return nil
+}
+
+// keep keeps the specified entry till the transaction is committed
+func (t *transaction) keep(entry *Entry) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.entries = append(t.entries, entry)
+}
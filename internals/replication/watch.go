@@ -0,0 +1,70 @@
+package replication
+
+import (
+	"errors"
+)
+
+// the watchers errors
+var (
+	ErrOffsetUnavailable = errors.New("the writes after the specified offset aren't in the backlog anymore")
+	ErrReplIDMismatch    = errors.New("the specified offset belongs to another write log")
+	ErrWatcherLost       = errors.New("the watcher couldn't keep up with the writes")
+	ErrWatcherClosed     = errors.New("the watcher has been closed")
+)
+
+// Watcher represents a subscription to the writes recorded in the write log
+type Watcher struct {
+	feed    *feed
+	pending []*Entry
+	done    chan struct{}
+}
+
+// Watch subscribes to the writes recorded after the specified offset of the write log having the specified id,
+// 0 means the current offset of the current write log, the watcher buffers up to the specified number of entries
+// before it is considered lost.
+func Watch(replid string, offset int64, buffer int) (*Watcher, error) {
+	w := &Watcher{done: make(chan struct{})}
+
+	if offset < 1 {
+		w.feed, _ = primary.backlog.subscribe(buffer)
+		return w, nil
+	}
+
+	pending, f, ok := primary.backlog.subscribeSince(replid, offset, buffer)
+	if !ok && replid != primary.backlog.id() {
+		return nil, ErrReplIDMismatch
+	}
+
+	if !ok {
+		return nil, ErrOffsetUnavailable
+	}
+
+	w.feed, w.pending = f, pending
+
+	return w, nil
+}
+
+// Next waits for the next recorded write, the pending entries of the backlog are returned first
+func (w *Watcher) Next() (*Entry, error) {
+	if len(w.pending) > 0 {
+		entry := w.pending[0]
+		w.pending = w.pending[1:]
+
+		return entry, nil
+	}
+
+	select {
+	case entry := <-w.feed.entries:
+		return entry, nil
+	case <-w.feed.lost:
+		return nil, ErrWatcherLost
+	case <-w.done:
+		return nil, ErrWatcherClosed
+	}
+}
+
+// Close cancels the subscription, the waiting Next call returns ErrWatcherClosed
+func (w *Watcher) Close() {
+	primary.backlog.unsubscribe(w.feed)
+	close(w.done)
+}
@@ -0,0 +1,89 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// the errors returned by Kill
+var (
+	ErrNotBusy     = errors.New("NOTBUSY No scripts in execution right now.")
+	ErrUnkillable  = errors.New("UNKILLABLE Sorry the script already executed write commands against the dataset. You can either wait the script termination or kill the server in a hard way using the SHUTDOWN NOSAVE command.")
+	errKilledReply = ErrorReply("ERR Script killed by user with SCRIPT KILL...")
+)
+
+// execution represents the state of the running script
+type execution struct {
+	startedAt time.Time
+	timeLimit time.Duration
+	killable  bool
+	wrote     bool
+	killed    bool
+	cancel    context.CancelFunc
+}
+
+var (
+	current     *execution
+	currentLock = &sync.Mutex{}
+
+	// runLock lets only one script run at a time
+	runLock = &sync.Mutex{}
+)
+
+// Busy whether a script is running for longer than its time limit or not
+func Busy() bool {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+
+	return current != nil && current.timeLimit > 0 && time.Since(current.startedAt) > current.timeLimit
+}
+
+// Kill stops the running script, a script that wrote can't be killed unless its writes can be discarded
+func Kill() error {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+
+	if current == nil {
+		return ErrNotBusy
+	}
+
+	if current.wrote && !current.killable {
+		return ErrUnkillable
+	}
+
+	current.killed = true
+	current.cancel()
+
+	return nil
+}
+
+// begin marks the specified execution as the running one
+func begin(exec *execution) {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+
+	current = exec
+}
+
+// end marks the running execution as finished, it reports whether it has been killed
+func end() bool {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+
+	killed := current.killed
+	current = nil
+
+	return killed
+}
+
+// markWrote records that the running script wrote
+func markWrote() {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+
+	if current != nil {
+		current.wrote = true
+	}
+}
@@ -0,0 +1,293 @@
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// FlagNoWrites the function flag marking the functions that don't modify the data
+const FlagNoWrites = "no-writes"
+
+// the flags the functions may declare, only FlagNoWrites changes how they are run
+var functionFlags = map[string]bool{
+	FlagNoWrites:            true,
+	"allow-oom":             true,
+	"allow-stale":           true,
+	"no-cluster":            true,
+	"allow-cross-slot-keys": true,
+}
+
+// the maximum duration the code registering the functions of a library may run for
+const libraryLoadTimeout = 500 * time.Millisecond
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// Library represents a compiled functions library
+type Library struct {
+	Name      string
+	Source    []byte
+	Functions []*Function
+
+	proto *lua.FunctionProto
+}
+
+// Function represents a function registered by a library
+type Function struct {
+	Name        string
+	Description string
+	Flags       []string
+}
+
+// registration represents a registered function along with its callback in a Lua state
+type registration struct {
+	fn       *Function
+	callback *lua.LFunction
+}
+
+// ReadOnly whether the function declared that it doesn't modify the data or not
+func (fn *Function) ReadOnly() bool {
+	for _, flag := range fn.Flags {
+		if flag == FlagNoWrites {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Function fetches the function of the library having the specified name if exists
+func (lib *Library) Function(name string) (*Function, bool) {
+	for _, fn := range lib.Functions {
+		if fn.Name == name {
+			return fn, true
+		}
+	}
+
+	return nil, false
+}
+
+// CompileLibrary compiles the specified library source which starts with a "#!lua name=<library>" line,
+// then runs it to collect the functions it registers using redis.register_function.
+func CompileLibrary(source []byte) (*Library, error) {
+	name, err := parseLibraryHeader(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// the header is a comment for lua, so it is compiled as is to keep the lines numbers
+	script, err := Compile("@user_function", bytes.Replace(source, []byte("#!"), []byte("--"), 1))
+	if err != nil {
+		return nil, fmt.Errorf("Error compiling function: %s", err.Error())
+	}
+
+	lib := &Library{Name: name, Source: source, proto: script.proto}
+
+	ctx, cancel := context.WithTimeout(context.Background(), libraryLoadTimeout)
+	defer cancel()
+
+	L := newState(&Options{})
+	defer L.Close()
+
+	L.SetContext(ctx)
+
+	registrations, err := register(L, lib)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range registrations {
+		lib.Functions = append(lib.Functions, r.fn)
+	}
+
+	sort.Slice(lib.Functions, func(i, j int) bool {
+		return lib.Functions[i].Name < lib.Functions[j].Name
+	})
+
+	return lib, nil
+}
+
+// RunFunction runs the specified function of the library then returns its reply like Run does,
+// the keys and the args are passed to the function as its two arguments.
+func RunFunction(lib *Library, name string, opts *Options) (interface{}, error) {
+	return run(opts, func(L *lua.LState) (lua.LValue, error) {
+		registrations, err := register(L, lib)
+		if err != nil {
+			return nil, err
+		}
+
+		r, exists := registrations[name]
+		if !exists {
+			return nil, ErrorReply("ERR Function not found")
+		}
+
+		L.Push(r.callback)
+		L.Push(newArgsTable(L, opts.Keys))
+		L.Push(newArgsTable(L, opts.Args))
+
+		if err := L.PCall(2, 1, nil); err != nil {
+			return nil, err
+		}
+
+		return L.Get(-1), nil
+	})
+}
+
+// parseLibraryHeader parses the "#!<engine> name=<library>" first line of a library source
+func parseLibraryHeader(source []byte) (string, error) {
+	if !bytes.HasPrefix(source, []byte("#!")) {
+		return "", fmt.Errorf("Missing library metadata")
+	}
+
+	header := string(source[2:])
+	if end := strings.IndexByte(header, '\n'); end > -1 {
+		header = header[:end]
+	}
+
+	parts := strings.Fields(header)
+	if len(parts) < 1 {
+		return "", fmt.Errorf("Missing library metadata")
+	}
+
+	if !strings.EqualFold(parts[0], "lua") {
+		return "", fmt.Errorf("Engine '%s' not found", parts[0])
+	}
+
+	name := ""
+
+	for _, part := range parts[1:] {
+		if !strings.HasPrefix(part, "name=") {
+			return "", fmt.Errorf("Invalid metadata value given: %s", part)
+		}
+
+		name = strings.TrimPrefix(part, "name=")
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("Library name was not given")
+	}
+
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("Library names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+
+	return name, nil
+}
+
+// register runs the library code in the specified state then returns the functions it registered,
+// the library code can't call the commands, it may only register its functions.
+func register(L *lua.LState, lib *Library) (map[string]*registration, error) {
+	registrations := map[string]*registration{}
+
+	redis := L.GetGlobal("redis").(*lua.LTable)
+
+	call, pcall := redis.RawGetString("call"), redis.RawGetString("pcall")
+
+	redis.RawSetString("call", lua.LNil)
+	redis.RawSetString("pcall", lua.LNil)
+	redis.RawSetString("register_function", L.NewFunction(func(L *lua.LState) int {
+		r, err := newRegistration(L)
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+		}
+
+		if _, exists := registrations[r.fn.Name]; exists {
+			L.RaiseError("Function already exists in the library")
+		}
+
+		registrations[r.fn.Name] = r
+
+		return 0
+	}))
+
+	L.Push(L.NewFunctionFromProto(lib.proto))
+
+	err := L.PCall(0, 0, nil)
+
+	redis.RawSetString("call", call)
+	redis.RawSetString("pcall", pcall)
+	redis.RawSetString("register_function", lua.LNil)
+
+	if apiErr, ok := err.(*lua.ApiError); ok {
+		return nil, fmt.Errorf("Error registering functions: %s", apiErr.Object.String())
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(registrations) < 1 {
+		return nil, fmt.Errorf("No functions registered")
+	}
+
+	return registrations, nil
+}
+
+// newRegistration parses the arguments of redis.register_function, it is called either
+// using the function name and the callback or using a table of the named arguments.
+func newRegistration(L *lua.LState) (*registration, error) {
+	r := &registration{fn: &Function{}}
+
+	switch arg := L.Get(1).(type) {
+	case lua.LString:
+		if L.GetTop() != 2 {
+			return nil, fmt.Errorf("wrong number of arguments to redis.register_function")
+		}
+
+		r.fn.Name = string(arg)
+		r.callback, _ = L.Get(2).(*lua.LFunction)
+	case *lua.LTable:
+		var err error
+
+		arg.ForEach(func(k, v lua.LValue) {
+			switch k.String() {
+			case "function_name":
+				r.fn.Name = v.String()
+			case "callback":
+				r.callback, _ = v.(*lua.LFunction)
+			case "description":
+				r.fn.Description = v.String()
+			case "flags":
+				flags, ok := v.(*lua.LTable)
+				if !ok {
+					err = fmt.Errorf("flags argument to redis.register_function must be a table representing function flags")
+					return
+				}
+
+				flags.ForEach(func(_, flag lua.LValue) {
+					if !functionFlags[flag.String()] {
+						err = fmt.Errorf("unknown flag given")
+					}
+
+					r.fn.Flags = append(r.fn.Flags, flag.String())
+				})
+			default:
+				err = fmt.Errorf("unknown argument given to redis.register_function")
+			}
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("calling redis.register_function with a single argument is only applicable to Lua table (representing named arguments).")
+	}
+
+	if !validName.MatchString(r.fn.Name) {
+		return nil, fmt.Errorf("Function names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+
+	if r.callback == nil {
+		return nil, fmt.Errorf("callback argument given to redis.register_function must be a function")
+	}
+
+	if r.fn.Flags == nil {
+		r.fn.Flags = []string{}
+	}
+
+	return r, nil
+}
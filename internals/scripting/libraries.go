@@ -0,0 +1,261 @@
+package scripting
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"sync"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// LibrariesPrefix the prefix of the keys the libraries are stored under, it is outside of the "/<db>/" namespaces,
+// so the libraries are shared by all the databases and by all the nodes sharing the same engine.
+const LibrariesPrefix = "/functions/"
+
+// the restore policies of RestoreLibraries
+const (
+	RestoreAppend  = "append"
+	RestoreReplace = "replace"
+	RestoreFlush   = "flush"
+)
+
+// the header of the payloads created by DumpLibraries
+var dumpHeader = []byte("REDIXFN1")
+
+// ErrLibraryNotFound is returned when deleting a library that doesn't exist
+var ErrLibraryNotFound = errors.New("Library not found")
+
+var (
+	// libraries caches the compiled libraries by the SHA1 digest of their sources
+	libraries     = map[string]*Library{}
+	librariesLock = &sync.Mutex{}
+)
+
+// Libraries fetches the libraries stored in the specified engine sorted by their names,
+// only the libraries that changed since the last call are compiled again.
+func Libraries(engine contract.Engine) ([]*Library, error) {
+	sources, err := librarySources(engine)
+	if err != nil {
+		return nil, err
+	}
+
+	librariesLock.Lock()
+	defer librariesLock.Unlock()
+
+	compiled := make(map[string]*Library, len(sources))
+	result := make([]*Library, 0, len(sources))
+
+	for _, source := range sources {
+		sha := SHA1Hex(source)
+
+		lib, exists := libraries[sha]
+		if !exists {
+			if lib, err = CompileLibrary(source); err != nil {
+				return nil, err
+			}
+		}
+
+		compiled[sha] = lib
+		result = append(result, lib)
+	}
+
+	libraries = compiled
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// FindFunction fetches the function having the specified name along with its library
+func FindFunction(engine contract.Engine, name string) (*Library, *Function, error) {
+	libs, err := Libraries(engine)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, lib := range libs {
+		if fn, exists := lib.Function(name); exists {
+			return lib, fn, nil
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// LoadLibrary compiles the specified library source then stores it in the engine, an existing library
+// having the same name is only replaced if requested, then it returns the library name.
+func LoadLibrary(engine contract.Engine, source []byte, replace bool) (string, error) {
+	lib, err := CompileLibrary(source)
+	if err != nil {
+		return "", err
+	}
+
+	libs, err := Libraries(engine)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkConflicts(lib, libs, replace); err != nil {
+		return "", err
+	}
+
+	if err := saveLibrary(engine, lib); err != nil {
+		return "", err
+	}
+
+	return lib.Name, nil
+}
+
+// DeleteLibrary removes the library having the specified name from the engine
+func DeleteLibrary(engine contract.Engine, name string) error {
+	// the deleting read removes the exact key, unlike the writes which remove the prefixed keys too
+	output, err := engine.Read(&contract.ReadInput{Key: libraryKey(name), Delete: true})
+	if err != nil {
+		return err
+	}
+
+	if output == nil || !output.Exists {
+		return ErrLibraryNotFound
+	}
+
+	return nil
+}
+
+// FlushLibraries removes all the libraries from the engine
+func FlushLibraries(engine contract.Engine) error {
+	_, err := engine.Write(&contract.WriteInput{Key: []byte(LibrariesPrefix), Value: nil})
+
+	return err
+}
+
+// DumpLibraries serializes all the libraries stored in the engine to be restored using RestoreLibraries
+func DumpLibraries(engine contract.Engine) ([]byte, error) {
+	sources, err := librarySources(engine)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := append([]byte{}, dumpHeader...)
+	size := make([]byte, binary.MaxVarintLen64)
+
+	for _, source := range sources {
+		payload = append(payload, size[:binary.PutUvarint(size, uint64(len(source)))]...)
+		payload = append(payload, source...)
+	}
+
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(payload))
+
+	return append(payload, checksum...), nil
+}
+
+// RestoreLibraries stores the libraries of the specified DumpLibraries payload in the engine using the specified policy,
+// RestoreAppend fails if any of them exists, RestoreReplace replaces the existing ones and RestoreFlush removes all of them first.
+func RestoreLibraries(engine contract.Engine, payload []byte, policy string) error {
+	if len(payload) < len(dumpHeader)+4 || !bytes.HasPrefix(payload, dumpHeader) {
+		return fmt.Errorf("payload version or checksum are wrong")
+	}
+
+	body, checksum := payload[:len(payload)-4], payload[len(payload)-4:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(checksum) {
+		return fmt.Errorf("payload version or checksum are wrong")
+	}
+
+	var restored []*Library
+
+	for body = body[len(dumpHeader):]; len(body) > 0; {
+		size, n := binary.Uvarint(body)
+		if n <= 0 || uint64(len(body)-n) < size {
+			return fmt.Errorf("payload version or checksum are wrong")
+		}
+
+		lib, err := CompileLibrary(body[n : n+int(size)])
+		if err != nil {
+			return err
+		}
+
+		restored = append(restored, lib)
+		body = body[n+int(size):]
+	}
+
+	if policy == RestoreFlush {
+		if err := FlushLibraries(engine); err != nil {
+			return err
+		}
+	}
+
+	libs, err := Libraries(engine)
+	if err != nil {
+		return err
+	}
+
+	for i, lib := range restored {
+		// the restored libraries must not conflict with each other either
+		if err := checkConflicts(lib, append(libs, restored[:i]...), policy == RestoreReplace); err != nil {
+			return err
+		}
+	}
+
+	for _, lib := range restored {
+		if err := saveLibrary(engine, lib); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkConflicts ensures that neither the library nor its functions already exist in the specified libraries,
+// the library itself (but not the functions of the other ones) may be replaced if requested.
+func checkConflicts(lib *Library, libs []*Library, replace bool) error {
+	for _, other := range libs {
+		if other.Name == lib.Name {
+			if !replace {
+				return fmt.Errorf("Library '%s' already exists", lib.Name)
+			}
+
+			continue
+		}
+
+		for _, fn := range lib.Functions {
+			if _, exists := other.Function(fn.Name); exists {
+				return fmt.Errorf("Function %s already exists", fn.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// saveLibrary stores the source of the specified library in the engine
+func saveLibrary(engine contract.Engine, lib *Library) error {
+	_, err := engine.Write(&contract.WriteInput{Key: libraryKey(lib.Name), Value: lib.Source})
+
+	return err
+}
+
+// librarySources fetches the sources of the libraries stored in the engine
+func librarySources(engine contract.Engine) ([][]byte, error) {
+	var sources [][]byte
+
+	err := engine.Iterate(&contract.IteratorOpts{
+		Prefix: []byte(LibrariesPrefix),
+		Callback: func(output *contract.ReadOutput) error {
+			sources = append(sources, append([]byte{}, output.Value...))
+			return nil
+		},
+	})
+
+	return sources, err
+}
+
+// libraryKey returns the key the library having the specified name is stored under
+func libraryKey(name string) []byte {
+	return []byte(LibrariesPrefix + name)
+}
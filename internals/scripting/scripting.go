@@ -0,0 +1,96 @@
+// Package scripting runs the Lua scripts sent by the clients
+package scripting
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Status represents a status reply (i.e OK)
+type Status string
+
+// ErrorReply represents an error reply, it starts with the error code (i.e ERR)
+type ErrorReply string
+
+// Error returns the error message
+func (e ErrorReply) Error() string {
+	return string(e)
+}
+
+// Script represents a compiled script
+type Script struct {
+	SHA    string
+	Source []byte
+
+	proto *lua.FunctionProto
+}
+
+var (
+	scripts     = map[string]*Script{}
+	scriptsLock = &sync.RWMutex{}
+)
+
+// Compile compiles the specified source without caching it, the name is used in the error messages
+func Compile(name string, source []byte) (*Script, error) {
+	chunk, err := parse.Parse(bytes.NewReader(source), name)
+	if err != nil {
+		return nil, err
+	}
+
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Script{SHA: SHA1Hex(source), Source: source, proto: proto}, nil
+}
+
+// Load compiles the specified source then caches it by its SHA1 digest
+func Load(source []byte) (*Script, error) {
+	if script, exists := Lookup(SHA1Hex(source)); exists {
+		return script, nil
+	}
+
+	script, err := Compile("user_script", source)
+	if err != nil {
+		return nil, fmt.Errorf("Error compiling script (new function): %s", err.Error())
+	}
+
+	scriptsLock.Lock()
+	scripts[script.SHA] = script
+	scriptsLock.Unlock()
+
+	return script, nil
+}
+
+// Lookup fetches the cached script having the specified SHA1 digest if exists
+func Lookup(sha string) (*Script, bool) {
+	scriptsLock.RLock()
+	defer scriptsLock.RUnlock()
+
+	script, exists := scripts[strings.ToLower(sha)]
+
+	return script, exists
+}
+
+// Flush removes all the cached scripts
+func Flush() {
+	scriptsLock.Lock()
+	defer scriptsLock.Unlock()
+
+	scripts = map[string]*Script{}
+}
+
+// SHA1Hex returns the hex encoded SHA1 digest of the specified data
+func SHA1Hex(data []byte) string {
+	sum := sha1.Sum(data)
+
+	return hex.EncodeToString(sum[:])
+}
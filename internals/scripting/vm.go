@@ -0,0 +1,301 @@
+package scripting
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// the log levels available to the scripts as redis.LOG_*
+const (
+	logDebug = iota
+	logVerbose
+	logNotice
+	logWarning
+)
+
+// Caller executes a command called by a script then returns its reply, the replies are
+// int64, []byte, nil, Status, ErrorReply or []interface{} of them.
+type Caller func(args [][]byte) interface{}
+
+// Options represents the options of a script run
+type Options struct {
+	// Keys and Args are exposed to the script as KEYS and ARGV
+	Keys [][]byte
+	Args [][]byte
+
+	// Call executes the commands called using redis.call and redis.pcall
+	Call Caller
+
+	// IsWrite reports whether the specified command arguments modify the data or not
+	IsWrite func(args [][]byte) bool
+
+	// TimeLimit the duration after which the script is considered busy (0 means never)
+	TimeLimit time.Duration
+
+	// Killable whether the script can still be killed after it wrote, i.e its writes are discarded
+	Killable bool
+}
+
+// Run runs the specified script then returns its reply, the returned error is an ErrorReply
+// describing why the script failed (including being killed), only one script runs at a time.
+func Run(script *Script, opts *Options) (interface{}, error) {
+	return run(opts, func(L *lua.LState) (lua.LValue, error) {
+		L.SetGlobal("KEYS", newArgsTable(L, opts.Keys))
+		L.SetGlobal("ARGV", newArgsTable(L, opts.Args))
+
+		L.Push(L.NewFunctionFromProto(script.proto))
+
+		if err := L.PCall(0, 1, nil); err != nil {
+			return nil, err
+		}
+
+		return L.Get(-1), nil
+	})
+}
+
+// run runs fn in a new sandboxed Lua state then converts its result to a reply
+func run(opts *Options, fn func(L *lua.LState) (lua.LValue, error)) (interface{}, error) {
+	runLock.Lock()
+	defer runLock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	L := newState(opts)
+	defer L.Close()
+
+	L.SetContext(ctx)
+
+	begin(&execution{
+		startedAt: time.Now(),
+		timeLimit: opts.TimeLimit,
+		killable:  opts.Killable,
+		cancel:    cancel,
+	})
+
+	ret, err := fn(L)
+
+	if killed := end(); killed {
+		return nil, errKilledReply
+	}
+
+	if err != nil {
+		return nil, errorReplyOf(err)
+	}
+
+	return fromLua(ret), nil
+}
+
+// newState creates a new Lua state having only the safe libraries and the redis one loaded
+func newState(opts *Options) *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	// the scripts must not access the filesystem
+	for _, name := range []string{"dofile", "loadfile", "module", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	redis := L.NewTable()
+
+	L.SetFuncs(redis, map[string]lua.LGFunction{
+		"call": func(L *lua.LState) int {
+			return call(L, opts, false)
+		},
+		"pcall": func(L *lua.LState) int {
+			return call(L, opts, true)
+		},
+		"error_reply": func(L *lua.LState) int {
+			L.Push(newReplyTable(L, "err", L.CheckString(1)))
+			return 1
+		},
+		"status_reply": func(L *lua.LState) int {
+			L.Push(newReplyTable(L, "ok", L.CheckString(1)))
+			return 1
+		},
+		"sha1hex": func(L *lua.LState) int {
+			L.Push(lua.LString(SHA1Hex([]byte(L.CheckString(1)))))
+			return 1
+		},
+		"log": func(L *lua.LState) int {
+			level := L.CheckInt(1)
+
+			parts := make([]string, 0, L.GetTop()-1)
+			for i := 2; i <= L.GetTop(); i++ {
+				parts = append(parts, L.Get(i).String())
+			}
+
+			if level >= logWarning {
+				log.Println("[WARN] (script)", strings.Join(parts, " "))
+			} else if level >= logNotice {
+				log.Println("[INFO] (script)", strings.Join(parts, " "))
+			}
+
+			return 0
+		},
+	})
+
+	redis.RawSetString("LOG_DEBUG", lua.LNumber(logDebug))
+	redis.RawSetString("LOG_VERBOSE", lua.LNumber(logVerbose))
+	redis.RawSetString("LOG_NOTICE", lua.LNumber(logNotice))
+	redis.RawSetString("LOG_WARNING", lua.LNumber(logWarning))
+
+	L.SetGlobal("redis", redis)
+
+	return L
+}
+
+// call executes the command passed to redis.call (or redis.pcall if protected),
+// the error replies are raised as errors unless protected.
+func call(L *lua.LState, opts *Options, protected bool) int {
+	if L.GetTop() < 1 {
+		L.RaiseError("Please specify at least one argument for this redis lib call")
+	}
+
+	args := make([][]byte, L.GetTop())
+
+	for i := range args {
+		switch v := L.Get(i + 1).(type) {
+		case lua.LString:
+			args[i] = []byte(string(v))
+		case lua.LNumber:
+			args[i] = []byte(v.String())
+		default:
+			L.RaiseError("Lua redis lib command arguments must be strings or integers")
+		}
+	}
+
+	if opts.IsWrite != nil && opts.IsWrite(args) {
+		markWrote()
+	}
+
+	reply := opts.Call(args)
+
+	if errReply, ok := reply.(ErrorReply); ok && !protected {
+		L.Error(newReplyTable(L, "err", string(errReply)), 1)
+	}
+
+	L.Push(toLua(L, reply))
+
+	return 1
+}
+
+// errorReplyOf converts the error raised by a script into an error reply
+func errorReplyOf(err error) ErrorReply {
+	if errReply, ok := err.(ErrorReply); ok {
+		return errReply
+	}
+
+	apiErr, ok := err.(*lua.ApiError)
+	if !ok {
+		return ErrorReply("ERR " + err.Error())
+	}
+
+	if tbl, ok := apiErr.Object.(*lua.LTable); ok {
+		if msg, ok := tbl.RawGetString("err").(lua.LString); ok {
+			return ErrorReply(msg)
+		}
+	}
+
+	return ErrorReply("ERR Error running script: " + apiErr.Object.String())
+}
+
+// newArgsTable creates a new Lua array of the specified arguments
+func newArgsTable(L *lua.LState, args [][]byte) *lua.LTable {
+	tbl := L.CreateTable(len(args), 0)
+
+	for _, arg := range args {
+		tbl.Append(lua.LString(arg))
+	}
+
+	return tbl
+}
+
+// newReplyTable creates a new status (ok) or error (err) reply table
+func newReplyTable(L *lua.LState, kind string, msg string) *lua.LTable {
+	tbl := L.NewTable()
+	tbl.RawSetString(kind, lua.LString(msg))
+
+	return tbl
+}
+
+// toLua converts the specified command reply to a Lua value
+func toLua(L *lua.LState, reply interface{}) lua.LValue {
+	switch reply := reply.(type) {
+	case int64:
+		return lua.LNumber(reply)
+	case []byte:
+		return lua.LString(reply)
+	case Status:
+		return newReplyTable(L, "ok", string(reply))
+	case ErrorReply:
+		return newReplyTable(L, "err", string(reply))
+	case []interface{}:
+		tbl := L.CreateTable(len(reply), 0)
+		for _, item := range reply {
+			tbl.Append(toLua(L, item))
+		}
+		return tbl
+	default:
+		return lua.LFalse
+	}
+}
+
+// fromLua converts the specified Lua value to a reply
+func fromLua(v lua.LValue) interface{} {
+	switch v := v.(type) {
+	case lua.LNumber:
+		return int64(v)
+	case lua.LString:
+		return []byte(string(v))
+	case lua.LBool:
+		if v {
+			return int64(1)
+		}
+		return nil
+	case *lua.LTable:
+		if msg, ok := v.RawGetString("err").(lua.LString); ok {
+			return ErrorReply(msg)
+		}
+
+		if msg, ok := v.RawGetString("ok").(lua.LString); ok {
+			return Status(msg)
+		}
+
+		var items []interface{}
+
+		for i := 1; ; i++ {
+			item := v.RawGetInt(i)
+			if item == lua.LNil {
+				break
+			}
+
+			items = append(items, fromLua(item))
+		}
+
+		if items == nil {
+			items = []interface{}{}
+		}
+
+		return items
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CommandStats represents the stats of a single command
+type CommandStats struct {
+	Name        string
+	Calls       int64
+	FailedCalls int64
+	Usec        int64
+}
+
+// Snapshot represents a point in time copy of the collected stats
+type Snapshot struct {
+	StartedAt           time.Time
+	ConnectionsReceived int64
+	CommandsProcessed   int64
+	ErrorReplies        int64
+	KeyspaceHits        int64
+	KeyspaceMisses      int64
+	PubSubChannels      int64
+	Commands            []CommandStats
+}
+
+var (
+	startedAt           = time.Now()
+	connectionsReceived int64
+	commandsProcessed   int64
+	errorReplies        int64
+	keyspaceHits        int64
+	keyspaceMisses      int64
+
+	commandsMap     = map[string]*CommandStats{}
+	commandsMapLock = &sync.Mutex{}
+
+	channelsMap     = map[string]int64{}
+	channelsMapLock = &sync.Mutex{}
+)
+
+// ConnectionReceived records a newly accepted connection
+func ConnectionReceived() {
+	atomic.AddInt64(&connectionsReceived, 1)
+}
+
+// ErrorReplied records an error reply sent to a client
+func ErrorReplied() {
+	atomic.AddInt64(&errorReplies, 1)
+}
+
+// KeyspaceHit records a successful key lookup
+func KeyspaceHit() {
+	atomic.AddInt64(&keyspaceHits, 1)
+}
+
+// KeyspaceMiss records a failed key lookup
+func KeyspaceMiss() {
+	atomic.AddInt64(&keyspaceMisses, 1)
+}
+
+// CommandProcessed records an executed command, its duration and whether it has failed or not
+func CommandProcessed(name string, d time.Duration, failed bool) {
+	atomic.AddInt64(&commandsProcessed, 1)
+
+	commandsMapLock.Lock()
+	defer commandsMapLock.Unlock()
+
+	cmd, exists := commandsMap[name]
+	if !exists {
+		cmd = &CommandStats{Name: name}
+		commandsMap[name] = cmd
+	}
+
+	cmd.Calls++
+	cmd.Usec += d.Microseconds()
+
+	if failed {
+		cmd.FailedCalls++
+	}
+}
+
+// ChannelSubscribed records a new subscription to the specified channel
+func ChannelSubscribed(channel string) {
+	channelsMapLock.Lock()
+	channelsMap[channel]++
+	channelsMapLock.Unlock()
+}
+
+// ChannelUnsubscribed records the end of a subscription to the specified channel
+func ChannelUnsubscribed(channel string) {
+	channelsMapLock.Lock()
+	defer channelsMapLock.Unlock()
+
+	channelsMap[channel]--
+	if channelsMap[channel] < 1 {
+		delete(channelsMap, channel)
+	}
+}
+
+// Take takes a snapshot of the current stats
+func Take() *Snapshot {
+	snapshot := Snapshot{
+		StartedAt:           startedAt,
+		ConnectionsReceived: atomic.LoadInt64(&connectionsReceived),
+		CommandsProcessed:   atomic.LoadInt64(&commandsProcessed),
+		ErrorReplies:        atomic.LoadInt64(&errorReplies),
+		KeyspaceHits:        atomic.LoadInt64(&keyspaceHits),
+		KeyspaceMisses:      atomic.LoadInt64(&keyspaceMisses),
+	}
+
+	channelsMapLock.Lock()
+	snapshot.PubSubChannels = int64(len(channelsMap))
+	channelsMapLock.Unlock()
+
+	commandsMapLock.Lock()
+	for _, cmd := range commandsMap {
+		snapshot.Commands = append(snapshot.Commands, *cmd)
+	}
+	commandsMapLock.Unlock()
+
+	sort.Slice(snapshot.Commands, func(i, j int) bool {
+		return snapshot.Commands[i].Name < snapshot.Commands[j].Name
+	})
+
+	return &snapshot
+}
@@ -0,0 +1,294 @@
+package writebehind
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+)
+
+// record types
+const (
+	recordWrite byte = 'W'
+	recordAck   byte = 'A'
+)
+
+// write input flags
+const (
+	flagIncrement byte = 1 << iota
+	flagAppend
+	flagOnlyIfNotExists
+	flagKeepTTL
+	flagNilKey
+	flagNilValue
+)
+
+// journal is an append only file that holds the queued writes and their acknowledgements,
+// every record is stored as: <length uint32><crc32 uint32><type byte><seq uint64><payload>
+type journal struct {
+	file *os.File
+	buf  *bufio.Writer
+}
+
+// pendingWrite represents a journaled write that hasn't been acknowledged
+type pendingWrite struct {
+	seq   uint64
+	input *contract.WriteInput
+}
+
+// openJournal opens (or creates) the journal file at the specified path
+// and returns the writes that haven't been acknowledged ordered by their sequence,
+// the caller must truncate the journal once these writes are recovered.
+func openJournal(path string) (*journal, []*pendingWrite, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pending, err := readJournal(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	j := &journal{
+		file: file,
+		buf:  bufio.NewWriter(file),
+	}
+
+	return j, pending, nil
+}
+
+// readJournal reads the journal records till the end of the file or the first torn/corrupted record
+func readJournal(file *os.File) ([]*pendingWrite, error) {
+	writes := map[uint64]*contract.WriteInput{}
+	rd := bufio.NewReader(file)
+	header := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(rd, header); err != nil {
+			break
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		sum := binary.BigEndian.Uint32(header[4:8])
+
+		body := make([]byte, size)
+		if _, err := io.ReadFull(rd, body); err != nil {
+			break
+		}
+
+		if crc32.ChecksumIEEE(body) != sum || len(body) < 9 {
+			break
+		}
+
+		seq := binary.BigEndian.Uint64(body[1:9])
+
+		switch body[0] {
+		case recordWrite:
+			input, err := decodeWriteInput(body[9:])
+			if err != nil {
+				return nil, err
+			}
+			writes[seq] = input
+		case recordAck:
+			delete(writes, seq)
+		}
+	}
+
+	pending := make([]*pendingWrite, 0, len(writes))
+	for seq, input := range writes {
+		pending = append(pending, &pendingWrite{seq: seq, input: input})
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].seq < pending[j].seq
+	})
+
+	return pending, nil
+}
+
+// appendWrite appends a write record
+func (j *journal) appendWrite(seq uint64, input *contract.WriteInput) error {
+	return j.append(recordWrite, seq, encodeWriteInput(input))
+}
+
+// appendAck appends an acknowledgement record of the specified write sequence
+func (j *journal) appendAck(seq uint64) error {
+	return j.append(recordAck, seq, nil)
+}
+
+// append appends a record to the journal buffer
+func (j *journal) append(typ byte, seq uint64, payload []byte) error {
+	body := make([]byte, 9, 9+len(payload))
+	body[0] = typ
+	binary.BigEndian.PutUint64(body[1:9], seq)
+	body = append(body, payload...)
+
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(body))
+
+	if _, err := j.buf.Write(header); err != nil {
+		return err
+	}
+
+	_, err := j.buf.Write(body)
+
+	return err
+}
+
+// sync flushes the journal buffer and commits the file to the disk
+func (j *journal) sync() error {
+	if err := j.flush(); err != nil {
+		return err
+	}
+
+	return j.fsync()
+}
+
+// flush writes the journal buffer to the file
+func (j *journal) flush() error {
+	return j.buf.Flush()
+}
+
+// fsync commits the file to the disk, it is safe to call it while appending
+func (j *journal) fsync() error {
+	return j.file.Sync()
+}
+
+// truncate empties the journal
+func (j *journal) truncate() error {
+	j.buf.Reset(j.file)
+
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := j.file.Seek(0, io.SeekStart)
+
+	return err
+}
+
+// close syncs then closes the journal file
+func (j *journal) close() error {
+	if err := j.sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+
+	return j.file.Close()
+}
+
+// encodeWriteInput encodes the specified input into bytes
+func encodeWriteInput(input *contract.WriteInput) []byte {
+	flags := byte(0)
+
+	if input.Increment {
+		flags |= flagIncrement
+	}
+
+	if input.Append {
+		flags |= flagAppend
+	}
+
+	if input.OnlyIfNotExists {
+		flags |= flagOnlyIfNotExists
+	}
+
+	if input.KeepTTL {
+		flags |= flagKeepTTL
+	}
+
+	if input.Key == nil {
+		flags |= flagNilKey
+	}
+
+	if input.Value == nil {
+		flags |= flagNilValue
+	}
+
+	tmp := make([]byte, binary.MaxVarintLen64)
+
+	buf := make([]byte, 0, 1+binary.MaxVarintLen64*3+len(input.Key)+len(input.Value))
+	buf = append(buf, flags)
+	buf = append(buf, tmp[:binary.PutVarint(tmp, int64(input.TTL))]...)
+	buf = append(buf, tmp[:binary.PutUvarint(tmp, uint64(len(input.Key)))]...)
+	buf = append(buf, input.Key...)
+	buf = append(buf, tmp[:binary.PutUvarint(tmp, uint64(len(input.Value)))]...)
+	buf = append(buf, input.Value...)
+
+	return buf
+}
+
+// decodeWriteInput decodes the specified bytes into a write input
+func decodeWriteInput(data []byte) (*contract.WriteInput, error) {
+	errInvalid := errors.New("invalid write-behind journal record")
+
+	if len(data) < 1 {
+		return nil, errInvalid
+	}
+
+	flags := data[0]
+	data = data[1:]
+
+	ttl, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, errInvalid
+	}
+	data = data[n:]
+
+	readBytes := func() ([]byte, error) {
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			return nil, errInvalid
+		}
+
+		b := make([]byte, size)
+		copy(b, data[n:n+int(size)])
+		data = data[n+int(size):]
+
+		return b, nil
+	}
+
+	key, err := readBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := readBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	input := &contract.WriteInput{
+		Key:             key,
+		Value:           value,
+		Increment:       flags&flagIncrement != 0,
+		Append:          flags&flagAppend != 0,
+		OnlyIfNotExists: flags&flagOnlyIfNotExists != 0,
+		KeepTTL:         flags&flagKeepTTL != 0,
+		TTL:             time.Duration(ttl),
+	}
+
+	if flags&flagNilKey != 0 {
+		input.Key = nil
+	}
+
+	if flags&flagNilValue != 0 {
+		input.Value = nil
+	}
+
+	if len(data) > 0 {
+		return nil, fmt.Errorf("%s (%d trailing bytes)", errInvalid.Error(), len(data))
+	}
+
+	return input, nil
+}
@@ -0,0 +1,482 @@
+package writebehind
+
+import (
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/metrics"
+)
+
+// Global vars
+var (
+	ErrQueueClosed = errors.New("the write-behind queue is closed")
+)
+
+// Options represents the write-behind queue options
+type Options struct {
+	// QueueSize the maximum number of pending writes, enqueuing blocks once it is reached
+	QueueSize int
+
+	// Workers the number of workers applying the writes, each key is always handled by the same worker
+	Workers int
+
+	// BatchSize the maximum number of writes applied to the engine at once
+	BatchSize int
+
+	// MaxRetries how many times a failed write is retried before being dropped
+	MaxRetries int
+
+	// Journal the path of the journal file used to recover the pending writes after a crash (optional),
+	// the writes are only acknowledged once they are committed to the disk
+	Journal string
+}
+
+// queued represents a queued write
+type queued struct {
+	seq   uint64
+	input *contract.WriteInput
+}
+
+// Queue is a bounded write-behind queue that applies the writes to the engine in background,
+// the writes of the same key are applied in the same order they have been enqueued.
+type Queue struct {
+	engine  contract.Engine
+	opts    Options
+	shards  []chan *queued
+	journal *journal
+
+	// sending the locks of the shards, each one is held while a write is sequenced and sent to its shard,
+	// so the shards receive their writes in the same order they have been journaled.
+	sending []sync.Mutex
+
+	seq     uint64
+	depth   int
+	closed  bool
+	drained *sync.Cond
+	mu      sync.Mutex
+
+	// synced the sequence of the latest write committed to the journal file, the concurrent writes
+	// are committed together (group commit) so they share a single fsync.
+	synced    uint64
+	committed *sync.Cond
+	syncErr   error
+	syncErrAt [2]uint64
+	pending   chan struct{}
+
+	workers sync.WaitGroup
+	syncer  sync.WaitGroup
+	stop    chan struct{}
+}
+
+// New creates a new write-behind queue for the specified engine, the pending writes
+// found in the journal (if any) are applied to the engine before it returns.
+func New(engine contract.Engine, opts Options) (*Queue, error) {
+	if opts.QueueSize < 1 {
+		opts.QueueSize = 10000
+	}
+
+	if opts.Workers < 1 {
+		opts.Workers = 4
+	}
+
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 1
+	}
+
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+
+	q := &Queue{
+		engine:  engine,
+		opts:    opts,
+		shards:  make([]chan *queued, opts.Workers),
+		sending: make([]sync.Mutex, opts.Workers),
+		stop:    make(chan struct{}),
+	}
+
+	q.drained = sync.NewCond(&q.mu)
+	q.committed = sync.NewCond(&q.mu)
+	q.pending = make(chan struct{}, 1)
+
+	if opts.Journal != "" {
+		j, pending, err := openJournal(opts.Journal)
+		if err != nil {
+			return nil, err
+		}
+
+		q.journal = j
+
+		for _, w := range pending {
+			if err := q.retry(func() error { return q.write(w.input) }); err != nil {
+				metrics.WriteBehindFailures.Inc()
+				log.Println("[ERROR] unable to recover a journaled write due to:", err.Error())
+			}
+		}
+
+		if err := j.truncate(); err != nil {
+			j.close()
+			return nil, err
+		}
+	}
+
+	shardSize := opts.QueueSize / opts.Workers
+	if shardSize < 1 {
+		shardSize = 1
+	}
+
+	for i := range q.shards {
+		q.shards[i] = make(chan *queued, shardSize)
+
+		q.workers.Add(1)
+		go q.work(q.shards[i])
+	}
+
+	if q.journal != nil {
+		q.syncer.Add(1)
+		go q.syncJournal()
+	}
+
+	return q, nil
+}
+
+// Enqueue adds a copy of the specified write to the queue, it blocks while the queue is full,
+// and till the write is committed to the journal if there is one.
+// the writes deleting the keys having a prefix (the ones without a value) may cover the keys of any shard,
+// so they block till they are applied after all the previous writes and before the next ones.
+func (q *Queue) Enqueue(input *contract.WriteInput) error {
+	item := &queued{input: copyWriteInput(input)}
+
+	var err error
+
+	if input.Key == nil || input.Value == nil {
+		err = q.sendBarrier(item)
+	} else {
+		err = q.send(item, q.shardOf(input.Key))
+	}
+
+	if err != nil || q.journal == nil {
+		return err
+	}
+
+	select {
+	case q.pending <- struct{}{}:
+	default:
+	}
+
+	return q.awaitCommit(item.seq)
+}
+
+// send sequences the specified write then sends it to the specified shard
+func (q *Queue) send(item *queued, shard int) error {
+	q.sending[shard].Lock()
+	defer q.sending[shard].Unlock()
+
+	if err := q.sequence(item); err != nil {
+		return err
+	}
+
+	q.shards[shard] <- item
+
+	return nil
+}
+
+// sendBarrier sequences the specified write then sends it to its shard once all the shards are drained,
+// the shards don't receive any other write till it is applied.
+func (q *Queue) sendBarrier(item *queued) error {
+	for i := range q.sending {
+		q.sending[i].Lock()
+		defer q.sending[i].Unlock()
+	}
+
+	q.Drain()
+
+	if err := q.sequence(item); err != nil {
+		return err
+	}
+
+	q.shards[q.shardOf(item.input.Key)] <- item
+
+	q.Drain()
+
+	return nil
+}
+
+// sequence assigns the next sequence to the specified write then journals it
+func (q *Queue) sequence(item *queued) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	q.seq++
+	item.seq = q.seq
+
+	if q.journal != nil {
+		if err := q.journal.appendWrite(item.seq, item.input); err != nil {
+			return err
+		}
+	}
+
+	q.depth++
+	metrics.WriteBehindDepth.Inc()
+
+	return nil
+}
+
+// awaitCommit blocks till the write of the specified sequence is committed to the journal
+func (q *Queue) awaitCommit(seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.synced < seq {
+		q.committed.Wait()
+	}
+
+	if q.syncErr != nil && seq > q.syncErrAt[0] && seq <= q.syncErrAt[1] {
+		return q.syncErr
+	}
+
+	return nil
+}
+
+// Drain blocks till all the pending writes are applied
+func (q *Queue) Drain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.depth > 0 {
+		q.drained.Wait()
+	}
+}
+
+// Close stops accepting new writes, waits for the pending ones to be applied then closes the journal
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	q.Drain()
+
+	close(q.stop)
+
+	for _, shard := range q.shards {
+		close(shard)
+	}
+
+	q.workers.Wait()
+	q.syncer.Wait()
+
+	if q.journal != nil {
+		return q.journal.close()
+	}
+
+	return nil
+}
+
+// shardOf returns the shard index that handles the specified key
+func (q *Queue) shardOf(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+
+	return int(h.Sum32() % uint32(len(q.shards)))
+}
+
+// work applies the writes of the specified shard in batches
+func (q *Queue) work(shard chan *queued) {
+	defer q.workers.Done()
+
+	batch := make([]*queued, 0, q.opts.BatchSize)
+
+	for item := range shard {
+		batch = append(batch[:0], item)
+
+	collect:
+		for len(batch) < q.opts.BatchSize {
+			select {
+			case item, ok := <-shard:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, item)
+			default:
+				break collect
+			}
+		}
+
+		q.apply(batch)
+	}
+}
+
+// apply applies the specified batch to the engine then acknowledges its writes
+func (q *Queue) apply(batch []*queued) {
+	failed := map[*queued]error{}
+
+	if batchWriter, ok := q.engine.(contract.BatchWriter); ok && len(batch) > 1 {
+		inputs := make([]*contract.WriteInput, len(batch))
+		for i, item := range batch {
+			inputs[i] = item.input
+		}
+
+		metrics.WriteBehindBatches.Inc()
+
+		// fallback to one by one writes, so a single bad write doesn't fail the whole batch
+		if err := q.retry(func() error { return batchWriter.WriteBatch(inputs) }); err != nil {
+			for _, item := range batch {
+				if err := q.retry(func() error { return q.write(item.input) }); err != nil {
+					failed[item] = err
+				}
+			}
+		}
+	} else {
+		for _, item := range batch {
+			metrics.WriteBehindBatches.Inc()
+
+			if err := q.retry(func() error { return q.write(item.input) }); err != nil {
+				failed[item] = err
+			}
+		}
+	}
+
+	for item, err := range failed {
+		metrics.WriteBehindFailures.Inc()
+		log.Printf("[ERROR] dropping the write-behind of key (%s) due to: %s\n", item.input.Key, err.Error())
+	}
+
+	metrics.WriteBehindWrites.Add(int64(len(batch) - len(failed)))
+
+	q.ack(batch)
+}
+
+// retry calls fn till it succeeds or the retries limit is reached
+func (q *Queue) retry(fn func() error) (err error) {
+	for attempt := 0; attempt <= q.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+
+		// there is no point in retrying a batch that can't be applied at once
+		if err = fn(); err == nil || err == contract.ErrBatchUnsupported {
+			return err
+		}
+	}
+
+	return err
+}
+
+// write applies a single write to the engine
+func (q *Queue) write(input *contract.WriteInput) error {
+	_, err := q.engine.Write(input)
+
+	return err
+}
+
+// ack acknowledges the specified writes, the journal is truncated once all the writes are acknowledged
+func (q *Queue) ack(batch []*queued) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.depth -= len(batch)
+
+	for range batch {
+		metrics.WriteBehindDepth.Dec()
+	}
+
+	if q.journal != nil {
+		var err error
+
+		if q.depth == 0 {
+			err = q.journal.truncate()
+		} else {
+			for _, item := range batch {
+				if err = q.journal.appendAck(item.seq); err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			log.Println("[ERROR] unable to update the write-behind journal due to:", err.Error())
+		}
+	}
+
+	if q.depth == 0 {
+		q.drained.Broadcast()
+	}
+}
+
+// syncJournal commits the journaled writes to the disk whenever there are uncommitted ones,
+// the writes journaled while a commit is in progress are committed together by the next one.
+func (q *Queue) syncJournal() {
+	defer q.syncer.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			// the writes enqueued right before closing are still awaiting
+			q.commit()
+			return
+		case <-q.pending:
+			q.commit()
+		}
+	}
+}
+
+// commit flushes the journaled writes then commits them to the disk, the fsync is done
+// without holding the queue lock, so the writes keep being journaled meanwhile.
+func (q *Queue) commit() {
+	q.mu.Lock()
+
+	from, to := q.synced, q.seq
+	if to <= from {
+		q.mu.Unlock()
+		return
+	}
+
+	err := q.journal.flush()
+
+	q.mu.Unlock()
+
+	if err == nil {
+		err = q.journal.fsync()
+	}
+
+	if err != nil {
+		log.Println("[ERROR] unable to sync the write-behind journal due to:", err.Error())
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err != nil {
+		q.syncErr, q.syncErrAt = err, [2]uint64{from, to}
+	}
+
+	q.synced = to
+	q.committed.Broadcast()
+}
+
+// copyWriteInput deep copies the specified input, since the connection buffers get reused
+func copyWriteInput(input *contract.WriteInput) *contract.WriteInput {
+	cp := *input
+
+	if input.Key != nil {
+		cp.Key = append([]byte{}, input.Key...)
+	}
+
+	if input.Value != nil {
+		cp.Value = append([]byte{}, input.Value...)
+	}
+
+	return &cp
+}
@@ -3,15 +3,32 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/alash3al/redix/internals/config"
+	"github.com/alash3al/redix/internals/datastore/cache"
 	"github.com/alash3al/redix/internals/datastore/contract"
+	"github.com/alash3al/redix/internals/datastore/router"
+	"github.com/alash3al/redix/internals/datastore/sharded"
+	"github.com/alash3al/redix/internals/grpcd"
+	"github.com/alash3al/redix/internals/httpd"
+	"github.com/alash3al/redix/internals/memcached"
+	"github.com/alash3al/redix/internals/metrics"
+	"github.com/alash3al/redix/internals/plugins"
 	"github.com/alash3al/redix/internals/redis"
+	"github.com/alash3al/redix/internals/redis/commands"
+	"github.com/alash3al/redix/internals/replication"
+	"github.com/alash3al/redix/internals/scripting"
+	"github.com/alash3al/redix/internals/writebehind"
 
 	_ "github.com/alash3al/redix/internals/datastore/engines/filesystem"
+	_ "github.com/alash3al/redix/internals/datastore/engines/memory"
 	_ "github.com/alash3al/redix/internals/datastore/engines/postgresql"
 )
 
@@ -19,6 +36,11 @@ var (
 	cfg *config.Config
 )
 
+const (
+	configWatchInterval    = time.Second * 2
+	defaultShutdownTimeout = time.Second * 10
+)
+
 func main() {
 	if len(os.Args) < 2 {
 		log.Fatal("you must specify the configuration file as an argument")
@@ -33,10 +55,380 @@ func main() {
 		log.Fatal("unable to load the config file due to: ", err.Error())
 	}
 
-	db, err := contract.Open(cfg.Engine.Driver, cfg.Engine.DSN)
+	// the configs are active before any listener starts, as they hold the users and the read only setting
+	config.Store(cfg)
+
+	if metricsCfg := cfg.Server.Metrics; metricsCfg != nil {
+		go (func() {
+			fmt.Println("=> started the metrics server on", metricsCfg.ListenAddr, "...")
+			if err := metrics.ListenAndServe(metricsCfg.ListenAddr, metricsCfg.Path); err != nil {
+				log.Fatal("failed to start the metrics server due to: ", err.Error())
+			}
+		})()
+	}
+
+	db, err := openEngines()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	replicationCfg := cfg.Server.Redis.Replication
+	if replicationCfg == nil {
+		replicationCfg = &config.ReplicationConfig{}
+	}
+
+	db = replication.Wrap(db, replicationCfg.BacklogSize)
+
+	if replicationCfg.ReplicaOf != "" {
+		fmt.Println("=> replicating", replicationCfg.ReplicaOf, "...")
+		replication.ReplicaOf(replicationCfg.ReplicaOf, db)
+	}
+
+	libraries, err := scripting.Libraries(db)
+	if err != nil {
+		log.Fatal("unable to load the functions libraries due to: ", err.Error())
+	}
+
+	fmt.Println("=> loaded", len(libraries), "functions libraries ...")
+
+	for _, pluginCfg := range cfg.Plugins {
+		if err := loadPlugin(pluginCfg); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	go handleReloadSignal()
+
+	if cfg.Server.WatchConfig {
+		go config.Watch(cfg.Filename, configWatchInterval, reloadConfig)
+	}
+
+	var queueOpts writebehind.Options
+	if writeBehindCfg := cfg.Server.Redis.WriteBehind; writeBehindCfg != nil {
+		queueOpts = writebehind.Options{
+			QueueSize:  writeBehindCfg.QueueSize,
+			Workers:    writeBehindCfg.Workers,
+			BatchSize:  writeBehindCfg.BatchSize,
+			MaxRetries: writeBehindCfg.MaxRetries,
+			Journal:    writeBehindCfg.Journal,
+		}
+	}
+
+	queue, err := writebehind.New(db, queueOpts)
+	if err != nil {
+		log.Fatal("failed to initialize the write-behind queue due to: ", err.Error())
+	}
+
+	srv := redis.NewServer(cfg, db, queue)
+
+	var httpSrv *httpd.Server
+	if httpCfg := cfg.Server.HTTP; httpCfg != nil {
+		httpSrv, err = httpd.NewServer(httpCfg, cfg.Server.Redis.TLS, db, queue)
+		if err != nil {
+			log.Fatal("failed to initialize the http server due to: ", err.Error())
+		}
+
+		go (func() {
+			fmt.Println("=> started the http server on", httpCfg.ListenAddr, "...")
+			if err := httpSrv.ListenAndServe(); err != nil {
+				log.Fatal("failed to start the http server due to: ", err.Error())
+			}
+		})()
+	}
+
+	var memcachedSrv *memcached.Server
+	if memcachedCfg := cfg.Server.Memcached; memcachedCfg != nil {
+		memcachedSrv = memcached.NewServer(memcachedCfg, db)
+
+		go (func() {
+			fmt.Println("=> started the memcached server on", memcachedCfg.ListenAddr, "...")
+			if err := memcachedSrv.ListenAndServe(); err != nil {
+				log.Fatal("failed to start the memcached server due to: ", err.Error())
+			}
+		})()
+	}
+
+	var grpcSrv *grpcd.Server
+	if grpcCfg := cfg.Server.GRPC; grpcCfg != nil {
+		grpcSrv, err = grpcd.NewServer(grpcCfg, cfg.Server.Redis.TLS, db, queue)
+		if err != nil {
+			log.Fatal("failed to initialize the grpc server due to: ", err.Error())
+		}
+
+		go (func() {
+			fmt.Println("=> started the grpc server on", grpcCfg.ListenAddr, "...")
+			if err := grpcSrv.ListenAndServe(); err != nil {
+				log.Fatal("failed to start the grpc server due to: ", err.Error())
+			}
+		})()
+	}
+
+	shutdownDone := make(chan struct{})
+	go handleShutdownSignal(srv, httpSrv, memcachedSrv, grpcSrv, db, queue, shutdownDone)
+
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatal("failed to start the redis server due to: ", err.Error())
+	}
+
+	<-shutdownDone
+}
+
+// handleShutdownSignal gracefully shuts down the servers, flushes the pending async writes
+// then closes the engine once a SIGTERM or SIGINT is received, a second signal forces the exit.
+func handleShutdownSignal(srv *redis.Server, httpSrv *httpd.Server, memcachedSrv *memcached.Server, grpcSrv *grpcd.Server, db contract.Engine, queue *writebehind.Queue, done chan struct{}) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	<-signals
+
+	fmt.Println("=> shutting down ...")
+
+	go (func() {
+		<-signals
+		log.Fatal("forced to exit before the shutdown has been completed")
+	})()
+
+	timeout := time.Duration(cfg.Server.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("[ERROR] the server hasn't been shutdown gracefully due to:", err.Error())
+	}
+
+	if httpSrv != nil {
+		if err := httpSrv.Shutdown(ctx); err != nil {
+			log.Println("[ERROR] the http server hasn't been shutdown gracefully due to:", err.Error())
+		}
+	}
+
+	if memcachedSrv != nil {
+		if err := memcachedSrv.Shutdown(ctx); err != nil {
+			log.Println("[ERROR] the memcached server hasn't been shutdown gracefully due to:", err.Error())
+		}
+	}
+
+	if grpcSrv != nil {
+		if err := grpcSrv.Shutdown(ctx); err != nil {
+			log.Println("[ERROR] the grpc server hasn't been shutdown gracefully due to:", err.Error())
+		}
+	}
+
+	replication.Stop()
+
+	queueClosed := make(chan error, 1)
+	go (func() {
+		queueClosed <- queue.Close()
+	})()
+
+	select {
+	case err := <-queueClosed:
+		if err != nil {
+			log.Println("[ERROR] unable to close the write-behind queue due to:", err.Error())
+		}
+	case <-ctx.Done():
+		log.Println("[ERROR] the pending async writes haven't been flushed due to:", ctx.Err().Error())
+	}
+
+	if err := db.Close(); err != nil {
+		log.Println("[ERROR] unable to close the engine due to:", err.Error())
+	}
+
+	fmt.Println("=> bye")
+
+	close(done)
+}
+
+// openEngines opens all of the configured engines, a router is placed in front of them if there are many
+func openEngines() (contract.Engine, error) {
+	engines := map[string]contract.Engine{}
+
+	closeAll := func() {
+		for _, opened := range engines {
+			opened.Close()
+		}
+	}
+
+	// the sharded engines are opened last, as they are composed of the other engines
+	for _, engineCfg := range cfg.Engines {
+		if engineCfg.Driver == sharded.Name {
+			continue
+		}
+
+		if len(engineCfg.Shards) > 0 {
+			closeAll()
+			return nil, fmt.Errorf("the engine (%s) isn't a %s engine to have shards", engineCfg.Name, sharded.Name)
+		}
+
+		db, err := openEngine(engineCfg)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		engines[engineCfg.Name] = db
+	}
+
+	for _, engineCfg := range cfg.Engines {
+		if engineCfg.Driver != sharded.Name {
+			continue
+		}
+
+		if engineCfg.Cache != nil {
+			closeAll()
+			return nil, fmt.Errorf("the %s engine (%s) can't be cached, configure the cache of its shards instead", sharded.Name, engineCfg.Name)
+		}
+
+		shards := map[string]contract.Engine{}
+
+		for _, name := range engineCfg.Shards {
+			shard, exists := engines[name]
+			if !exists {
+				closeAll()
+				return nil, fmt.Errorf("the shard (%s) of the engine (%s) can't be a %s engine", name, engineCfg.Name, sharded.Name)
+			}
+
+			shards[name] = shard
+			delete(engines, name)
+		}
+
+		db, err := sharded.New(shards, engineCfg.Shards)
+		if err != nil {
+			for _, shard := range shards {
+				shard.Close()
+			}
+			closeAll()
+			return nil, fmt.Errorf("failed to open the engine (%s) due to: %s", engineCfg.Name, err.Error())
+		}
+
+		// the keys misplaced by adding a shard are moved in background, they are moved on access meanwhile
+		go (func(name string) {
+			moved, err := db.Rebalance()
+			if err != nil {
+				log.Println("[ERROR] unable to rebalance the engine", name, "due to:", err.Error())
+				return
+			}
+
+			if moved > 0 {
+				fmt.Println("=> moved", moved, "misplaced keys of the engine", name, "...")
+			}
+		})(engineCfg.Name)
+
+		engines[engineCfg.Name] = db
+	}
+
+	if len(engines) == 1 {
+		return engines[cfg.DefaultEngine().Name], nil
+	}
+
+	var routes []*router.Route
+
+	for _, routeCfg := range cfg.Routes {
+		route := &router.Route{
+			DB:     -1,
+			Prefix: []byte(routeCfg.Prefix),
+			Engine: routeCfg.Engine,
+		}
+
+		if routeCfg.DB != nil {
+			route.DB = *routeCfg.DB
+		}
+
+		routes = append(routes, route)
+	}
+
+	return router.New(engines, routes, cfg.DefaultEngine().Name)
+}
+
+// openEngine opens the specified engine, then instruments and caches it if configured
+func openEngine(engineCfg *config.EngineConfig) (contract.Engine, error) {
+	db, err := contract.Open(engineCfg.Driver, engineCfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the engine (%s) due to: %s", engineCfg.Name, err.Error())
+	}
+
+	if engineCfg.ReplicaDSN != "" {
+		replicator, ok := db.(contract.ReadReplicator)
+		if !ok {
+			db.Close()
+			return nil, fmt.Errorf("the engine (%s) doesn't support reading from a replica", engineCfg.Name)
+		}
+
+		if err := replicator.OpenReadReplica(engineCfg.ReplicaDSN); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open the read replica of the engine (%s) due to: %s", engineCfg.Name, err.Error())
+		}
+	}
+
+	if cfg.Server.Metrics != nil {
+		db = metrics.InstrumentEngine(db)
+	}
+
+	if cacheCfg := engineCfg.Cache; cacheCfg != nil {
+		cached, err := cache.New(db, cache.Options{
+			Policy:      cacheCfg.Policy,
+			MaxKeys:     cacheCfg.MaxKeys,
+			TTL:         time.Duration(cacheCfg.TTL) * time.Second,
+			Distributed: cacheCfg.Distributed,
+			Channel:     cacheCfg.Channel,
+		})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize the cache of the engine (%s) due to: %s", engineCfg.Name, err.Error())
+		}
+
+		db = cached
+	}
+
+	return db, nil
+}
+
+// loadPlugin loads the specified plugin module then registers its commands
+func loadPlugin(pluginCfg *config.PluginConfig) error {
+	timeLimit := pluginCfg.TimeLimit
+	if timeLimit <= 0 {
+		timeLimit = config.DefaultPluginTimeLimit
+	}
+
+	plugin, err := plugins.Open(pluginCfg.Name, pluginCfg.Path, time.Duration(timeLimit)*time.Millisecond)
 	if err != nil {
-		log.Fatal("failed to open database connection due to: ", err.Error())
+		return fmt.Errorf("failed to load the plugin (%s) due to: %s", pluginCfg.Name, err.Error())
+	}
+
+	if err := commands.RegisterPlugin(plugin); err != nil {
+		return err
 	}
 
-	redis.ListenAndServe(cfg, db)
+	fmt.Println("=> loaded the plugin", pluginCfg.Name, "providing", plugin.Commands, "...")
+
+	return nil
+}
+
+// handleReloadSignal reloads the configs whenever a SIGHUP is received
+func handleReloadSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		reloadConfig()
+	}
+}
+
+// reloadConfig reloads the configs file and reports the result
+func reloadConfig() {
+	applied, ignored, err := config.Reload()
+	if err != nil {
+		log.Println("[ERROR] unable to reload the configs due to:", err.Error())
+		return
+	}
+
+	fmt.Println("=> reloaded the configs, applied settings:", applied)
+
+	if len(ignored) > 0 {
+		log.Println("[WARN] the following changed settings require a restart to take effect:", ignored)
+	}
 }
@@ -6,20 +6,168 @@ server {
         // which [address]:portNumber to let the server listen on
         listen = ":6380"
 
+        // a unix socket path to listen on, in addition to (or instead of) the above "listen" address,
+        // a stale socket file left behind by a previous run will be removed.
+        // unix_socket = "/var/run/redix/redix.sock"
+
+        // the permissions (octal) of the unix socket file
+        // unix_socket_permissions = "0770"
+
         // maximum number of connections allowed to the server instance in the same time
         max_connections = 100
 
+        // close the connection after a client is idle for N seconds (0 to disable)
+        timeout = 0
+
+        // whether to reject the commands that modify the data or not, useful during the maintenance
+        read_only = false
+
         // whether to let the writes be async (done in background) or not?
         async = false
+
+        // the async writes are applied in background through a bounded write-behind queue,
+        // the writes of the same key are applied in order, it defaults to the following values if not specified.
+        // write_behind {
+        //     // the maximum number of pending writes, the clients are blocked once it is reached
+        //     queue_size = 10000
+        //
+        //     // the number of the background workers
+        //     workers = 4
+        //
+        //     // the maximum number of writes applied at once (in a single transaction when using "postgresql")
+        //     batch_size = 1
+        //
+        //     // how many times a failed write is retried before being dropped
+        //     max_retries = 0
+        //
+        //     // an optional journal file to recover the pending writes after a crash,
+        //     // the writes are acknowledged once committed to it (the concurrent ones share a single fsync),
+        //     // without it the pending writes are lost on a crash.
+        //     journal = "./redix.journal"
+        // }
+
+        // the replication streams the writes of a primary node to its replicas, which reject the writes,
+        // a replica falling too far behind its primary is fully resynchronized, it defaults to the following values if not specified.
+        // replication {
+        //     // the address of the primary to replicate, the node is a primary if not specified
+        //     replica_of = "primary.local:6380"
+        //
+        //     // the number of the latest writes kept to let the reconnecting replicas continue where they stopped
+        //     backlog_size = 10000
+        // }
+
+        // the lua scripts (EVAL, EVALSHA, ...) run one at a time, the commands touching the data wait for them,
+        // a script running for longer than the following time limit (in milliseconds, 0 to disable) makes them fail with a BUSY error
+        // until it finishes or gets killed using SCRIPT KILL, it defaults to the following values if not specified.
+        // scripting {
+        //     time_limit = 5000
+        // }
+
+        // the slow log records the commands that exceeded the specified execution time,
+        // it defaults to the following values if not specified.
+        // slowlog {
+        //     // the execution time threshold in microseconds,
+        //     // a zero value logs every command while a negative one disables the slow log.
+        //     log_slower_than = 10000
+        //
+        //     // the maximum number of entries to keep
+        //     max_len = 128
+        // }
+
+        // uncomment to enable tls, if no "listen" is specified here,
+        // the above "listen" address will be served over tls only,
+        // otherwise both plaintext and tls listeners will run side by side.
+        // tls {
+        //     listen = ":6381"
+        //     cert_file = "/etc/redix/tls/server.crt"
+        //     key_file = "/etc/redix/tls/server.key"
+        //
+        //     // specifying a client ca file enables the mutual-tls mode
+        //     client_ca_file = "/etc/redix/tls/ca.crt"
+        //
+        //     // the minimum accepted tls version: "1.0", "1.1", "1.2" (default) or "1.3"
+        //     min_version = "1.2"
+        // }
     }
+
+    // whether to watch the configurations file and reload it whenever it changes,
+    // sending SIGHUP to the redix process reloads it as well.
+    // only the settings that can be changed at runtime (see CONFIG SET) and the users are reloaded,
+    // the others are reported and require a restart.
+    watch_config = false
+
+    // the maximum number of seconds to wait for the in-flight commands and the pending
+    // async writes to finish while shutting down (SIGTERM/SIGINT), defaults to 10 seconds.
+    shutdown_timeout = 10
+
+    // uncomment to expose the keys and the pub/sub channels over http (see the http interface docs),
+    // the clients must authenticate once a user block is declared, otherwise only expose it to the trusted networks,
+    // it uses the tls block of the redis listener if set, so the same certificates authenticate the clients (client_ca_file).
+    // http {
+    //     // which [address]:portNumber to let the http server listen on
+    //     listen = ":6381"
+    //
+    //     // the origins of the browsers allowed to open the websocket gateway, "*" allows any origin,
+    //     // only the pages served from the http server origin are allowed by default.
+    //     allowed_origins = ["https://app.example.com"]
+    // }
+
+    // uncomment to serve the keys over the memcached text protocol (see the memcached interface docs),
+    // it shares the engine with the redis listener and has no authentication too.
+    // memcached {
+    //     // which [address]:portNumber to let the memcached server listen on
+    //     listen = ":11211"
+    //
+    //     // the db index (as selected by SELECT) the memcached keys are stored in
+    //     db = 0
+    // }
+
+    // uncomment to serve the keys over grpc (see the grpc interface docs), it uses the tls block
+    // of the redis listener if set, so the same certificates authenticate the clients (client_ca_file).
+    // grpc {
+    //     // which [address]:portNumber to let the grpc server listen on
+    //     listen = ":6382"
+    // }
+
+    // uncomment to expose the prometheus metrics over http
+    // metrics {
+    //     // which [address]:portNumber to let the metrics server listen on
+    //     listen = ":9180"
+    //
+    //     // the http path to expose the metrics on, defaults to "/metrics"
+    //     path = "/metrics"
+    // }
 }
 
 // redix is modular, "we can have multiple storage engines to store the data"
-// currently the supported engines are "postgresql" and "filesystem".
+// currently the supported engines are "postgresql", "filesystem", "memory" and "sharded".
 // in case you want to connect to "postgresql":
 engine "postgresql" {
     // data-source-name regarding postgresql server configurations
      dsn = "postgresql://postgres@localhost/redix"
+
+    // an optional read-only replica the reads are served from, the writes still go to the dsn
+    // replica_dsn = "postgresql://postgres@replica.local/redix"
+
+    // an optional read-through in-memory cache in front of the engine,
+    // it defaults to the following values if not specified.
+    // cache {
+    //     // the eviction policy used once max_keys is reached, "lru" or "lfu"
+    //     policy = "lru"
+    //
+    //     // the maximum number of the cached keys
+    //     max_keys = 10000
+    //
+    //     // the maximum number of seconds a key is kept cached, the key own TTL is honoured if it is shorter
+    //     ttl = 60
+    //
+    //     // whether to invalidate the keys cached by the other redix nodes sharing the same backend
+    //     // using its publish/subscribe or not, the engine must support publish/subscribe (i.e "postgresql")
+    //     distributed = false
+    //
+    //     // the channel the invalidations are published to
+    //     channel = "__redix_cache_invalidations__"
+    // }
 }
 
 // in case you want "filesystem" to be your backend:
@@ -27,3 +175,54 @@ engine "postgresql" {
     // data-source-name: the directory to store the data files in
 //   dsn = "./data/"
 //}
+
+// in case you want an in-process "memory" engine (the data is lost on restart):
+//engine "memory" {
+    // the engine name used by the routes, defaults to the driver name,
+    // it is required when declaring multiple engines of the same driver.
+//   name = "sessions"
+//}
+
+// in case you want to spread the keys over multiple engines using consistent hashing,
+// the shards are other engines referenced by their names and are only reachable through the "sharded" engine,
+// the keys a shard takes over once added are moved to it in background on startup (and on access meanwhile),
+// the REBALANCE command moves them on demand.
+//engine "sharded" {
+    // the names of the shards engines
+//   shards = ["pg1", "pg2"]
+//}
+
+// multiple engines can be declared, the first one is the default engine that stores the keys
+// not matching any of the routes, the routes are evaluated in order and each of them matches
+// the keys of a db index (as selected by SELECT) and/or having a key prefix.
+//route {
+    // the db index
+//   db = 1
+//
+    // the key prefix
+//   prefix = "session:"
+//
+    // the engine name the matched keys are stored in
+//   engine = "sessions"
+//}
+
+// the plugins are WebAssembly modules providing custom commands, each exported function
+// named "command_<name>" is registered as the command <name>, see the "plugins" package for the host API.
+//plugin "example" {
+    // the path of the module file
+//   path = "./plugins/example.wasm"
+//
+    // the duration (in milliseconds) after which a command call is aborted, defaults to 5000
+//   time_limit = 5000
+//}
+
+// the users of the http interface, once a user is declared the clients must authenticate
+// (basic authorization or the websocket "auth" action) and each user only reaches the channels
+// matching its channels patterns (as path.Match, so "*" doesn't match "/").
+//user "alice" {
+    // the user password
+//   password = "${ALICE_PASSWORD}"
+//
+    // the patterns of the channels the user may publish and subscribe to
+//   channels = ["news", "news/*"]
+//}